@@ -2,8 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -15,15 +22,48 @@ var opt = kpvotes.Options{
 	IsDebug: false,
 }
 
+var fixOpt = kpvotes.FixOptions{}
+
+var cacheOpt = kpvotes.CacheOptions{}
+
+const cacheBackendUsage = "imdb cache backend: " + kpvotes.IMDbCacheBackendMemory +
+	" (JSON lines file loaded at start and saved at exit) or " +
+	kpvotes.IMDbCacheBackendBolt + " (bbolt database file written through)"
+
+const (
+	cacheFileUsage = "imdb titles cache file path; for the " + kpvotes.IMDbCacheBackendMemory +
+		" backend also a directory or a glob pattern of the shared cache files to merge"
+	cacheSaveUsage = "imdb titles cache file path to save the merged cache to, " +
+		"defaults to the imdb_cache if it is a single file; " + kpvotes.IMDbCacheBackendMemory + " backend only"
+	cacheConflictsUsage = "policy for the cached titles with different IDs in the merged files: " +
+		string(imdb.ConflictPolicyNewest) + " or " + string(imdb.ConflictPolicyPreferOverride)
+)
+
+// exitCodeInterrupted is an exit code of the run interrupted by the signal.
+const exitCodeInterrupted = 130
+
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		// The repeated signal kills the process without waiting for the partial results to be saved.
+		stop()
+	}()
 
 	cmd := initCommand(ctx)
 
-	// TODO: listen to signals
+	err := cmd.ExecuteContext(ctx)
+	if errors.Is(err, kpvotes.ErrInterrupted) {
+		log.Warn(err.Error())
+		stop()
+
+		os.Exit(exitCodeInterrupted)
+	}
 
-	if err := cmd.ExecuteContext(ctx); err != nil {
+	if err != nil {
 		log.Sugar().Fatalf("failed with error: %s", err)
 	}
 
@@ -49,20 +89,269 @@ func initCommand(ctx context.Context) *cobra.Command {
 	}
 
 	root.Flags().StringVar(&opt.TargetFile, "target", "", "target .csv file path")
-	root.Flags().StringVar(&opt.IMDbCacheFile, "imdb_cache", "", "imdb titles cache file path")
+	root.Flags().StringVar(&opt.IMDbCacheFile, "imdb_cache", "", cacheFileUsage)
+	root.Flags().StringVar(&opt.IMDbCacheSaveFile, "imdb_cache_save", "", cacheSaveUsage)
+	root.Flags().StringVar(
+		&opt.IMDbCacheConflicts, "imdb_cache_conflicts", string(imdb.ConflictPolicyNewest),
+		cacheConflictsUsage,
+	)
+	root.Flags().StringVar(
+		&opt.IMDbCacheBackend, "imdb_cache_backend", kpvotes.IMDbCacheBackendMemory,
+		cacheBackendUsage,
+	)
+	root.Flags().BoolVar(
+		&opt.IMDbCacheJournal, "imdb_cache_journal", false,
+		"append only the newly resolved titles to the cache file instead of rewriting it; "+
+			kpvotes.IMDbCacheBackendMemory+" backend only",
+	)
+	root.Flags().StringVar(
+		&opt.IMDbOverridesFile, "overrides", "",
+		"CSV file with the manual IMDb IDs (or \"skip\") keyed by the kinopoisk film URL or ID",
+	)
 	root.Flags().UintVar(
 		&opt.TargetChunkSize, "chunk_size", 0,
 		"if set, the target file will be split to the chunks of the defined size",
 	)
-	root.Flags().BoolVar(&opt.IsDebug, "debug", false, "enable the debug mode")
+	root.Flags().Float64Var(
+		&opt.IMDbMinConfidence, "imdb_min_confidence", imdb.DefaultMinConfidence,
+		"minimal score from 0 to 1 of the IMDb search result to be accepted",
+	)
+	root.Flags().Float64Var(
+		&opt.IMDbReviewConfidence, "review_confidence", kpvotes.DefaultReviewConfidence,
+		"score the matches below are written to the <target>.review.csv to be checked and fixed, "+
+			"the matches of unknown score (e.g. the legacy cache entries) are not, 0 to disable",
+	)
+	root.Flags().DurationVar(
+		&opt.IMDbMissTTL, "imdb_miss_ttl", imdb.DefaultMissTTL,
+		"time the not found titles are cached for and not resolved again, 0 to disable",
+	)
+	root.Flags().BoolVar(
+		&opt.IMDbRetryMisses, "retry_misses", false,
+		"resolve the titles cached as not found again",
+	)
+	root.Flags().StringSliceVar(
+		&opt.IMDbResolvers, "resolvers", nil,
+		"comma-separated IMDb resolvers chain, asked in order until one finds the ID: "+
+			strings.Join([]string{
+				kpvotes.IMDbResolverOverrides,
+				kpvotes.IMDbResolverCache,
+				kpvotes.IMDbResolverWikidata + " (wikidata by kinopoisk ID)",
+				kpvotes.IMDbResolverKinopoisk + " (kinopoisk film page)",
+				kpvotes.IMDbResolverDataset + " (offline IMDb dataset)",
+				kpvotes.IMDbResolverFind + " (imdb.com search)",
+				kpvotes.IMDbResolverSuggest + " (IMDb suggestion API)",
+			}, ", ")+
+			" (default "+strings.Join(opt.GetIMDbResolvers(), ",")+")",
+	)
+	root.Flags().StringVar(
+		&opt.IMDbDatasetDir, "imdb_dataset_dir", "",
+		"directory with the downloaded IMDb "+imdb.DatasetBasicsFile+" and optional "+imdb.DatasetAkasFile,
+	)
+	root.Flags().StringVar(
+		&opt.IMDbSuggestURL, "imdb_suggest_url", imdb.SuggestURL,
+		"base URL of the IMDb suggestion API",
+	)
+	root.Flags().StringVar(
+		&opt.WikidataEndpoint, "wikidata_endpoint", imdb.WikidataEndpoint,
+		"Wikidata SPARQL endpoint URL or path to the local SPARQL JSON results file",
+	)
+	root.Flags().UintVar(
+		&opt.Workers, "workers", 2,
+		"max number of the votes pages downloaded concurrently, "+
+			"raise it together with the kinopoisk --rate, the workers above the rate per second only wait for it",
+	)
+	root.Flags().UintVar(
+		&opt.ResolveWorkers, "resolve_workers", 8,
+		"max number of the IMDb IDs resolved concurrently",
+	)
+	root.Flags().StringVar(
+		&opt.CookiesFile, "cookies", "",
+		"Netscape cookies.txt or JSON cookies file exported from the browser "+
+			"with the kinopoisk session to read the private votes; sent to kinopoisk only",
+	)
+	root.Flags().StringVar(
+		&opt.RateLimits, "rate", "kinopoisk.ru=2/s",
+		"comma-separated max requests rates by the host, applied to its subdomains too, "+
+			"e.g. kinopoisk.ru=2/s,imdb.com=5/s; the other hosts are not limited. "+
+			"The default kinopoisk rate lets the default --workers run in parallel "+
+			"and is kept low to not be blocked by kinopoisk",
+	)
+	root.Flags().UintVar(
+		&opt.Retry.Attempts, "retries", downloader.DefaultRetryAttempts,
+		"max number of the attempts of each request failed with a transient error, 1 to disable retries",
+	)
+	root.Flags().DurationVar(
+		&opt.Retry.BaseDelay, "retry_delay", downloader.DefaultRetryBaseDelay,
+		"delay before the first retry, doubled for each next one",
+	)
+	root.Flags().DurationVar(
+		&opt.Retry.MaxDelay, "retry_max_delay", downloader.DefaultRetryMaxDelay,
+		"max delay between the retries, longer Retry-After responses are not retried",
+	)
+	root.Flags().BoolVar(
+		&opt.WriteAudit, "audit", false,
+		"write the JSON report of the IMDb IDs sources and confidence next to the target file",
+	)
+	root.PersistentFlags().BoolVar(&opt.IsDebug, "debug", false, "enable the debug mode")
 	root.Flags().Var(&opt.UserID, "uid", "kinopoisk user ID")
 
 	_ = root.MarkFlagRequired("target")
 	_ = root.MarkFlagRequired("uid")
 
+	root.AddCommand(initFixCommand(ctx))
+	root.AddCommand(initCacheCommand(ctx))
+
 	return root
 }
 
+func initFixCommand(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fix",
+		Short: "Apply corrections from the report",
+		Long: "Apply the IMDb IDs filled in the 'IMDb ID' column of the unmatched or review votes report " +
+			"to the imdb cache and overrides files, so the next export uses them. " +
+			"Use \"skip\" as an ID to exclude the film from the export.",
+
+		SilenceErrors: true,
+		SilenceUsage:  true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			initLogger()
+
+			return kpvotes.Fix(ctx, log, fixOpt)
+		},
+	}
+
+	cmd.Flags().StringVar(&fixOpt.ReportFile, "report", "", "report .csv file path with the filled IMDb IDs")
+	cmd.Flags().StringVar(&fixOpt.IMDbCacheFile, "imdb_cache", "", cacheFileUsage)
+	cmd.Flags().StringVar(&fixOpt.IMDbCacheSaveFile, "imdb_cache_save", "", cacheSaveUsage)
+	cmd.Flags().StringVar(
+		&fixOpt.IMDbCacheConflicts, "imdb_cache_conflicts", string(imdb.ConflictPolicyNewest),
+		cacheConflictsUsage,
+	)
+	cmd.Flags().StringVar(
+		&fixOpt.IMDbCacheBackend, "imdb_cache_backend", kpvotes.IMDbCacheBackendMemory,
+		cacheBackendUsage,
+	)
+	cmd.Flags().StringVar(&fixOpt.IMDbOverridesFile, "overrides", "", "overrides .csv file path")
+
+	_ = cmd.MarkFlagRequired("report")
+
+	return cmd
+}
+
+func initCacheCommand(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the imdb titles cache",
+		Long: "Inspect and edit the imdb titles cache. " +
+			"The entries are addressed by the kinopoisk film URL or ID, or by the title in the \"Title (year)\" format.",
+	}
+
+	cmd.PersistentFlags().StringVar(&cacheOpt.IMDbCacheFile, "imdb_cache", "", cacheFileUsage)
+	cmd.PersistentFlags().StringVar(&cacheOpt.IMDbCacheSaveFile, "imdb_cache_save", "", cacheSaveUsage)
+	cmd.PersistentFlags().StringVar(
+		&cacheOpt.IMDbCacheConflicts, "imdb_cache_conflicts", string(imdb.ConflictPolicyNewest),
+		cacheConflictsUsage,
+	)
+	cmd.PersistentFlags().StringVar(
+		&cacheOpt.IMDbCacheBackend, "imdb_cache_backend", kpvotes.IMDbCacheBackendMemory,
+		cacheBackendUsage,
+	)
+	cmd.PersistentFlags().DurationVar(
+		&cacheOpt.IMDbMissTTL, "imdb_miss_ttl", imdb.DefaultMissTTL,
+		"time the not found titles are cached for, expired ones are removed, 0 to keep all",
+	)
+	cmd.PersistentFlags().StringVar(
+		&cacheOpt.OutputFormat, "format", kpvotes.OutputFormatTable,
+		"output format: "+kpvotes.OutputFormatTable+" or "+kpvotes.OutputFormatJSON,
+	)
+
+	_ = cmd.MarkPersistentFlagRequired("imdb_cache")
+
+	var (
+		filter string
+		title  string
+	)
+
+	list := newCacheSubcommand(
+		"list", "List the cache entries", cobra.NoArgs,
+		func(args []string) error {
+			return kpvotes.ListCache(ctx, log, cacheOpt, filter, os.Stdout)
+		},
+	)
+	list.Flags().StringVar(&filter, "filter", "", "show only the entries having the substring in the title")
+
+	set := newCacheSubcommand(
+		"set <kinopoisk URL, ID or title> <IMDb ID>", "Set the IMDb ID of the film", cobra.ExactArgs(2),
+		func(args []string) error {
+			return kpvotes.SetCache(ctx, log, cacheOpt, args[0], args[1], title)
+		},
+	)
+	set.Flags().StringVar(&title, "title", "", "film title in the \"Title (year)\" format")
+
+	cmd.AddCommand(
+		list,
+		newCacheSubcommand(
+			"get <kinopoisk URL, ID or title>", "Show the cache entry", cobra.ExactArgs(1),
+			func(args []string) error {
+				return kpvotes.GetCache(ctx, log, cacheOpt, args[0], os.Stdout)
+			},
+		),
+		set,
+		newCacheSubcommand(
+			"delete <kinopoisk URL, ID or title>", "Delete the cache entry", cobra.ExactArgs(1),
+			func(args []string) error {
+				return kpvotes.DeleteCache(ctx, log, cacheOpt, args[0])
+			},
+		),
+		newCacheSubcommand(
+			"stats", "Show the cache entries counts", cobra.NoArgs,
+			func(args []string) error {
+				return kpvotes.CacheStats(ctx, log, cacheOpt, os.Stdout)
+			},
+		),
+		newCacheSubcommand(
+			"import <file>", "Merge the entries from the JSON lines file into the cache", cobra.ExactArgs(1),
+			func(args []string) error {
+				return kpvotes.ImportCache(ctx, log, cacheOpt, args[0])
+			},
+		),
+		newCacheSubcommand(
+			"export <file>", "Write the cache entries to the JSON lines file", cobra.ExactArgs(1),
+			func(args []string) error {
+				return kpvotes.ExportCache(ctx, log, cacheOpt, args[0])
+			},
+		),
+		newCacheSubcommand(
+			"compact", "Rewrite the cache file without duplicates and expired entries", cobra.NoArgs,
+			func(args []string) error {
+				return kpvotes.CompactCache(ctx, log, cacheOpt)
+			},
+		),
+	)
+
+	return cmd
+}
+
+func newCacheSubcommand(use string, short string, args cobra.PositionalArgs, run func(args []string) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  args,
+
+		SilenceErrors: true,
+		SilenceUsage:  true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Output is printed to the stdout, so the logs are written to the stderr.
+			log = logger.NewStderrConsoleLogger(opt.IsDebug)
+
+			return run(args)
+		},
+	}
+}
+
 func initLogger() {
 	log = logger.NewDefaultConsoleLogger(opt.IsDebug)
 }
@@ -0,0 +1,280 @@
+package kpvotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"go.uber.org/zap"
+)
+
+// CompactCache rewrites the cache file removing the duplicated and expired entries.
+// The cache directory or pattern files are merged into the save file.
+func CompactCache(ctx context.Context, log *zap.Logger, opt CacheOptions) error {
+	if opt.IMDbCacheBackend != "" && opt.IMDbCacheBackend != IMDbCacheBackendMemory {
+		return fmt.Errorf("only the %s cache backend file can be compacted", IMDbCacheBackendMemory)
+	}
+
+	target := opt.GetIMDbCacheSaveFile()
+	if target == "" {
+		return errors.New("imdb cache save file is required to compact the cache directory or pattern")
+	}
+
+	log = log.With(zap.String("who", "cache"), zap.String("cache", opt.IMDbCacheFile))
+
+	var before int64
+
+	if stat, err := os.Stat(target); err == nil {
+		before = stat.Size()
+	}
+
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	if err := cache.ExportTitlesIDs(ctx, target, false); err != nil {
+		return err
+	}
+
+	after, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Cache compacted from %d to %d byte(s)", before, after.Size()))
+
+	return nil
+}
+
+// openCache opens the IMDb cache of the backend.
+// The memory cache is imported from the JSON lines files, the bolt one is opened from the database file.
+func openCache(ctx context.Context, log *zap.Logger, opt CacheStorageOptions) (imdb.Cache, error) {
+	policy, err := imdb.ParseConflictPolicy(opt.IMDbCacheConflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opt.IMDbCacheBackend {
+	case "", IMDbCacheBackendMemory:
+		cache := imdb.NewMemoryCache(log, opt.IMDbMissTTL, policy)
+
+		if opt.IMDbCacheFile != "" {
+			if err := cache.ImportTitlesIDs(ctx, opt.IMDbCacheFile); err != nil {
+				return nil, err
+			}
+		}
+
+		return cache, nil
+	case IMDbCacheBackendBolt:
+		if opt.IMDbCacheFile == "" || imdb.IsCachePattern(opt.IMDbCacheFile) {
+			return nil, errors.New("imdb cache database file is required for the bolt cache backend")
+		}
+
+		// The bolt cache is written through to its file, there is nothing to save on close.
+		if opt.IMDbCacheSaveFile != "" {
+			return nil, errors.New("imdb cache save file is not supported by the bolt cache backend")
+		}
+
+		return imdb.NewBoltCache(log, opt.IMDbCacheFile, opt.IMDbMissTTL, policy)
+	default:
+		return nil, fmt.Errorf("unknown IMDb cache backend '%s'", opt.IMDbCacheBackend)
+	}
+}
+
+// closeCache closes the persistent cache or exports the in-memory one to the path, if set.
+func closeCache(ctx context.Context, cache imdb.Cache, path string, isAppend bool) error {
+	if closer, ok := cache.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	return cache.ExportTitlesIDs(ctx, path, isAppend)
+}
+
+// ListCache prints the cache entries having the filter substring in the title, all if the filter is empty.
+func ListCache(ctx context.Context, log *zap.Logger, opt CacheOptions, filter string, out io.Writer) error {
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = closeCache(ctx, cache, "", false)
+	}()
+
+	entries, err := cache.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter = strings.ToLower(filter)
+	filtered := make([]imdb.CacheEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Title), filter) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return printCacheEntries(out, opt, filtered)
+}
+
+// GetCache prints the cache entry by the kinopoisk film URL, ID or title.
+func GetCache(ctx context.Context, log *zap.Logger, opt CacheOptions, key string, out io.Writer) error {
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = closeCache(ctx, cache, "", false)
+	}()
+
+	entry, ok, err := cache.Get(ctx, cacheQuery(key))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("no cache entry for '%s'", key)
+	}
+
+	return printCacheEntry(out, opt, entry)
+}
+
+// SetCache stores the IMDb ID for the kinopoisk film URL, ID or title.
+// If the title is empty, the title of the existing entry is kept.
+func SetCache(ctx context.Context, log *zap.Logger, opt CacheOptions, key string, id string, title string) error {
+	imdbID := imdb.TitleID(id)
+	if !imdbID.IsValid() {
+		return fmt.Errorf("invalid IMDb ID '%s'", id)
+	}
+
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	query := cacheQuery(key)
+
+	if title != "" {
+		query.Title = title
+	} else if existing, ok, err := cache.Get(ctx, query); err == nil && ok {
+		query.Title = existing.Title
+	}
+
+	err = cache.Store(ctx, imdb.NewCacheEntry(query, imdbID, imdb.Match{Source: imdb.SourceOverride, Confidence: 1}))
+	if err != nil {
+		_ = closeCache(ctx, cache, "", false)
+
+		return err
+	}
+
+	return closeCache(ctx, cache, opt.GetIMDbCacheSaveFile(), false)
+}
+
+// DeleteCache removes the cache entry by the kinopoisk film URL, ID or title.
+func DeleteCache(ctx context.Context, log *zap.Logger, opt CacheOptions, key string) error {
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	query := cacheQuery(key)
+
+	_, ok, err := cache.Get(ctx, query)
+	if err == nil && !ok {
+		err = fmt.Errorf("no cache entry for '%s'", key)
+	}
+
+	if err == nil {
+		err = cache.Invalidate(ctx, query)
+	}
+
+	if err != nil {
+		_ = closeCache(ctx, cache, "", false)
+
+		return err
+	}
+
+	return closeCache(ctx, cache, opt.GetIMDbCacheSaveFile(), false)
+}
+
+// CacheStats prints the cache entries counts.
+func CacheStats(ctx context.Context, log *zap.Logger, opt CacheOptions, out io.Writer) error {
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = closeCache(ctx, cache, "", false)
+	}()
+
+	entries, err := cache.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	return printCacheStats(out, opt, newCacheStats(entries, opt.IMDbMissTTL))
+}
+
+// ImportCache merges the entries from the JSON lines file, directory or glob pattern into the cache.
+func ImportCache(ctx context.Context, log *zap.Logger, opt CacheOptions, sourcePath string) error {
+	paths, err := imdb.CacheSourcePaths(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("no files to import by %s", sourcePath)
+	}
+
+	if _, err := os.Stat(paths[0]); err != nil {
+		return fmt.Errorf("no file to import: %w", err)
+	}
+
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	if err := cache.ImportTitlesIDs(ctx, sourcePath); err != nil {
+		_ = closeCache(ctx, cache, "", false)
+
+		return err
+	}
+
+	return closeCache(ctx, cache, opt.GetIMDbCacheSaveFile(), false)
+}
+
+// ExportCache writes the cache entries to the JSON lines file.
+func ExportCache(ctx context.Context, log *zap.Logger, opt CacheOptions, targetPath string) error {
+	cache, err := openCache(ctx, log, opt.CacheStorageOptions)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = closeCache(ctx, cache, "", false)
+	}()
+
+	return cache.ExportTitlesIDs(ctx, targetPath, false)
+}
+
+// cacheQuery returns the query by the kinopoisk film URL or ID, or by the title.
+func cacheQuery(key string) imdb.Query {
+	if _, ok := imdb.ParseKinopoiskFilmID(key); ok {
+		return imdb.Query{KinopoiskURL: key}
+	}
+
+	return imdb.Query{Title: key}
+}
@@ -0,0 +1,101 @@
+package kpvotes_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheCommands(t *testing.T) {
+	for _, backend := range []string{kpvotes.IMDbCacheBackendMemory, kpvotes.IMDbCacheBackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			log := logger.NewDefaultConsoleLogger(true)
+			dir := t.TempDir()
+
+			opt := kpvotes.CacheOptions{
+				CacheStorageOptions: kpvotes.CacheStorageOptions{
+					IMDbCacheFile:    filepath.Join(dir, "cache"),
+					IMDbCacheBackend: backend,
+					IMDbMissTTL:      imdb.DefaultMissTTL,
+				},
+				OutputFormat: kpvotes.OutputFormatJSON,
+			}
+
+			importPath := filepath.Join(dir, "import.jsonl")
+			require.NoError(t, os.WriteFile(importPath, []byte(`{"title":"Legacy (2020)","id":"tt0000001"}`+"\n"), 0644))
+
+			require.NoError(t, kpvotes.ImportCache(ctx, log, opt, importPath))
+			require.NoError(t, kpvotes.SetCache(
+				ctx, log, opt, "https://www.kinopoisk.ru/film/4910679/", "tt17009710", "Anatomie d'une chute (2023)",
+			))
+			assert.Error(t, kpvotes.SetCache(ctx, log, opt, "4910679", "invalid", ""))
+
+			out := &bytes.Buffer{}
+			require.NoError(t, kpvotes.GetCache(ctx, log, opt, "4910679", out))
+			assert.Contains(t, out.String(), `"imdb_id": "tt17009710"`)
+			assert.Contains(t, out.String(), `"source": "override"`)
+
+			out.Reset()
+			require.NoError(t, kpvotes.ListCache(ctx, log, opt, "ANATOMIE", out))
+			assert.Contains(t, out.String(), "tt17009710")
+			assert.NotContains(t, out.String(), "tt0000001")
+
+			require.NoError(t, kpvotes.DeleteCache(ctx, log, opt, "Legacy (2020)"))
+			assert.Error(t, kpvotes.DeleteCache(ctx, log, opt, "Legacy (2020)"))
+
+			// The entry stored by the kinopoisk film ID is addressed by its title too.
+			require.NoError(t, kpvotes.SetCache(
+				ctx, log, opt, "https://www.kinopoisk.ru/film/474953/", "tt1515091", "Sherlock Holmes (2011)",
+			))
+
+			out.Reset()
+			require.NoError(t, kpvotes.GetCache(ctx, log, opt, "Sherlock Holmes (2011)", out))
+			assert.Contains(t, out.String(), `"imdb_id": "tt1515091"`)
+
+			require.NoError(t, kpvotes.DeleteCache(ctx, log, opt, "Sherlock Holmes (2011)"))
+			assert.Error(t, kpvotes.GetCache(ctx, log, opt, "474953", out))
+
+			out.Reset()
+			require.NoError(t, kpvotes.CacheStats(ctx, log, opt, out))
+			assert.Contains(t, out.String(), `"total": 1`)
+
+			opt.OutputFormat = kpvotes.OutputFormatTable
+
+			out.Reset()
+			require.NoError(t, kpvotes.ListCache(ctx, log, opt, "", out))
+			assert.Regexp(t, `(?m)^4910679\s+Anatomie d'une chute \(2023\)\s+tt17009710\s+found\s+override`, out.String())
+
+			exportPath := filepath.Join(dir, "export.jsonl")
+			require.NoError(t, kpvotes.ExportCache(ctx, log, opt, exportPath))
+
+			exported, err := os.ReadFile(exportPath)
+			require.NoError(t, err)
+			assert.Contains(t, string(exported), `"kinopoisk_id":"4910679"`)
+		})
+	}
+}
+
+func TestCacheCommands_WhenBoltCacheSaveFileSet(t *testing.T) {
+	dir := t.TempDir()
+
+	opt := kpvotes.CacheOptions{
+		CacheStorageOptions: kpvotes.CacheStorageOptions{
+			IMDbCacheFile:     filepath.Join(dir, "cache.db"),
+			IMDbCacheSaveFile: filepath.Join(dir, "cache.jsonl"),
+			IMDbCacheBackend:  kpvotes.IMDbCacheBackendBolt,
+		},
+	}
+
+	err := kpvotes.ListCache(context.Background(), logger.NewDefaultConsoleLogger(true), opt, "", &bytes.Buffer{})
+
+	assert.ErrorContains(t, err, "not supported by the bolt cache backend")
+}
@@ -0,0 +1,165 @@
+package kpvotes
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+)
+
+const (
+	cacheStatusFound   = "found"
+	cacheStatusMiss    = "miss"
+	cacheStatusExpired = "expired"
+)
+
+type cacheEntryView struct {
+	KinopoiskID string  `json:"kinopoisk_id,omitempty"`
+	Title       string  `json:"title,omitempty"`
+	IMDbID      string  `json:"imdb_id,omitempty"`
+	Status      string  `json:"status"`
+	Source      string  `json:"source,omitempty"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	ResolvedAt  string  `json:"resolved_at,omitempty"`
+}
+
+func newCacheEntryView(entry imdb.CacheEntry, missTTL time.Duration) cacheEntryView {
+	view := cacheEntryView{
+		KinopoiskID: entry.KinopoiskID,
+		Title:       entry.Title,
+		IMDbID:      entry.ID.String(),
+		Status:      cacheStatusFound,
+		Source:      string(entry.Source),
+		Confidence:  entry.Confidence,
+	}
+
+	switch {
+	case entry.IsExpiredMiss(missTTL):
+		view.Status = cacheStatusExpired
+	case entry.IsMiss():
+		view.Status = cacheStatusMiss
+	}
+
+	if !entry.ResolvedAt.IsZero() {
+		view.ResolvedAt = entry.ResolvedAt.Format(time.RFC3339)
+	}
+
+	return view
+}
+
+type cacheStats struct {
+	Total              int            `json:"total"`
+	Found              int            `json:"found"`
+	Misses             int            `json:"misses"`
+	ExpiredMisses      int            `json:"expired_misses"`
+	WithoutKinopoiskID int            `json:"without_kinopoisk_id"`
+	BySource           map[string]int `json:"by_source"`
+}
+
+func newCacheStats(entries []imdb.CacheEntry, missTTL time.Duration) cacheStats {
+	stats := cacheStats{Total: len(entries), BySource: make(map[string]int)}
+
+	for _, entry := range entries {
+		switch {
+		case entry.IsExpiredMiss(missTTL):
+			stats.ExpiredMisses++
+		case entry.IsMiss():
+			stats.Misses++
+		default:
+			stats.Found++
+		}
+
+		if entry.KinopoiskID == "" {
+			stats.WithoutKinopoiskID++
+		}
+
+		source := string(entry.Source)
+		if source == "" {
+			source = "unknown"
+		}
+
+		stats.BySource[source]++
+	}
+
+	return stats
+}
+
+func printCacheEntries(out io.Writer, opt CacheOptions, entries []imdb.CacheEntry) error {
+	views := make([]cacheEntryView, 0, len(entries))
+	for _, entry := range entries {
+		views = append(views, newCacheEntryView(entry, opt.IMDbMissTTL))
+	}
+
+	if opt.OutputFormat == OutputFormatJSON {
+		return printJSON(out, views)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "KINOPOISK ID\tTITLE\tIMDB ID\tSTATUS\tSOURCE\tCONFIDENCE\tRESOLVED AT")
+
+	for _, view := range views {
+		confidence := ""
+		if view.Confidence > 0 {
+			confidence = strconv.FormatFloat(view.Confidence, 'f', 2, 64)
+		}
+
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			view.KinopoiskID, view.Title, view.IMDbID, view.Status, view.Source, confidence, view.ResolvedAt,
+		)
+	}
+
+	return w.Flush()
+}
+
+func printCacheEntry(out io.Writer, opt CacheOptions, entry imdb.CacheEntry) error {
+	if opt.OutputFormat == OutputFormatJSON {
+		return printJSON(out, newCacheEntryView(entry, opt.IMDbMissTTL))
+	}
+
+	return printCacheEntries(out, opt, []imdb.CacheEntry{entry})
+}
+
+func printCacheStats(out io.Writer, opt CacheOptions, stats cacheStats) error {
+	if opt.OutputFormat == OutputFormatJSON {
+		return printJSON(out, stats)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(w, "Total:\t%d\n", stats.Total)
+	_, _ = fmt.Fprintf(w, "Found:\t%d\n", stats.Found)
+	_, _ = fmt.Fprintf(w, "Misses:\t%d\n", stats.Misses)
+	_, _ = fmt.Fprintf(w, "Expired misses:\t%d\n", stats.ExpiredMisses)
+	_, _ = fmt.Fprintf(w, "Without kinopoisk ID:\t%d\n", stats.WithoutKinopoiskID)
+
+	sources := make([]string, 0, len(stats.BySource))
+	for source := range stats.BySource {
+		sources = append(sources, source)
+	}
+
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		_, _ = fmt.Fprintf(w, "Source %s:\t%d\n", source, stats.BySource[source])
+	}
+
+	return w.Flush()
+}
+
+func printJSON(out io.Writer, v any) error {
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	_, err = out.Write(append(data, '\n'))
+
+	return err
+}
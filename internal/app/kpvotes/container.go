@@ -2,24 +2,37 @@ package kpvotes
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/kukymbr/godi"
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/reader"
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/resolver"
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/writer"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
 	"go.uber.org/zap"
 )
 
 const (
-	diLogger         = "logger"
-	diImdbCache      = "imdb_cache"
-	diImdbDataLoader = "imdb_dataloader"
-	diVotesReader    = "votes_reader"
-	diVotesWriter    = "votes_writer"
-	diRunner         = "runner"
+	diLogger          = "logger"
+	diImdbCache       = "imdb_cache"
+	diImdbDataLoader  = "imdb_dataloader"
+	diImdbDataset     = "imdb_dataset"
+	diRateLimiter     = "rate_limiter"
+	diCookieJar       = "cookie_jar"
+	diVotesReader     = "votes_reader"
+	diVotesResolver   = "votes_resolver"
+	diVotesWriter     = "votes_writer"
+	diUnmatchedWriter = "unmatched_writer"
+	diReviewWriter    = "review_writer"
+	diAuditWriter     = "audit_writer"
+	diRunner          = "runner"
 )
 
 func buildContainer(ctx context.Context, log *zap.Logger, opt Options) (*godi.Container, error) {
@@ -47,26 +60,77 @@ func getBuilder(ctx context.Context, log *zap.Logger, opt Options) (*godi.Builde
 			},
 		},
 		godi.Def{
-			Name: diImdbCache,
+			Name: diRateLimiter,
+			Build: func(_ *godi.Container) (obj any, err error) {
+				limits, err := downloader.ParseRateLimits(opt.RateLimits)
+				if err != nil {
+					return nil, err
+				}
+
+				return downloader.NewRateLimiter(limits), nil
+			},
+		},
+		godi.Def{
+			Name: diCookieJar,
 			Build: func(ctn *godi.Container) (obj any, err error) {
-				cache := imdb.NewMemoryCache(requireLogger(ctn))
+				var cookies []*http.Cookie
 
-				if opt.IMDbCacheFile != "" {
-					if err := cache.ImportTitlesIDs(ctx, opt.IMDbCacheFile); err != nil {
+				if opt.CookiesFile != "" {
+					cookies, err = downloader.LoadCookies(opt.CookiesFile)
+					if err != nil {
 						return nil, err
 					}
+
+					logger.RedactSecrets(downloader.CookieValues(cookies)...)
+				}
+
+				jar, err := downloader.NewCookieJar(cookies, kinopoisk.CookieDomain)
+				if err != nil {
+					return nil, err
+				}
+
+				requireLogger(ctn).Debug(fmt.Sprintf("%d kinopoisk cookie(s) loaded", len(cookies)))
+
+				return jar, nil
+			},
+		},
+		godi.Def{
+			Name: diImdbCache,
+			Build: func(ctn *godi.Container) (obj any, err error) {
+				logger := requireLogger(ctn)
+
+				if opt.IMDbCacheJournal && opt.IMDbCacheBackend == IMDbCacheBackendBolt {
+					return nil, errors.New("imdb cache journal is not supported by the bolt cache backend")
+				}
+
+				if opt.IMDbCacheFile != "" && opt.GetIMDbCacheSaveFile() == "" {
+					logger.Warn("IMDb cache is read from the directory or pattern and will not be saved, " +
+						"set the cache save file to save it")
 				}
 
-				return cache, nil
+				return openCache(ctx, logger, opt.CacheStorageOptions)
 			},
 			Close: func(obj any) (err error) {
-				cache := obj.(imdb.Cache)
+				err = closeCache(context.Background(), obj.(imdb.Cache), opt.GetIMDbCacheSaveFile(), opt.IMDbCacheJournal)
+				if err != nil {
+					log.Warn("Failed to save IMDb cache: " + err.Error())
+				}
 
-				if opt.IMDbCacheFile != "" {
-					_ = cache.ExportTitlesIDs(context.Background(), opt.IMDbCacheFile, true)
+				return err
+			},
+		},
+		godi.Def{
+			Name: diImdbDataset,
+			Lazy: true,
+			Build: func(ctn *godi.Container) (obj any, err error) {
+				if opt.IMDbDatasetDir == "" {
+					return nil, fmt.Errorf("IMDb dataset dir is required for the %s resolver", IMDbResolverDataset)
 				}
 
-				return nil
+				return imdb.OpenDataset(ctx, requireLogger(ctn), opt.IMDbDatasetDir)
+			},
+			Close: func(obj any) (err error) {
+				return obj.(*imdb.Dataset).Close()
 			},
 		},
 		godi.Def{
@@ -74,15 +138,27 @@ func getBuilder(ctx context.Context, log *zap.Logger, opt Options) (*godi.Builde
 			Build: func(ctn *godi.Container) (obj any, err error) {
 				logger := requireLogger(ctn)
 
-				return imdb.NewDataLoader(
-					logger,
-					downloader.NewStdDownloader(
-						logger,
-						imdb.TimeoutFind,
-						opt.ProxyURL,
-					),
-					requireImdbCache(ctn),
-				), nil
+				var overrides imdb.Overrides
+
+				if opt.IMDbOverridesFile != "" {
+					overrides, err = imdb.LoadOverrides(logger, opt.IMDbOverridesFile)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				links := make([]imdb.DataLoader, 0)
+
+				for _, name := range opt.GetIMDbResolvers() {
+					link, err := buildImdbResolver(ctn, opt, name, overrides)
+					if err != nil {
+						return nil, err
+					}
+
+					links = append(links, link)
+				}
+
+				return imdb.NewChainDataLoader(logger, requireImdbCache(ctn), links...), nil
 			},
 		},
 		godi.Def{
@@ -92,12 +168,18 @@ func getBuilder(ctx context.Context, log *zap.Logger, opt Options) (*godi.Builde
 
 				return reader.NewVotesReader(
 					logger,
-					downloader.NewStdDownloader(
-						logger,
-						kinopoisk.TimeoutVotes,
-						opt.ProxyURL,
-					),
+					newDownloader(ctn, opt, kinopoisk.TimeoutVotes, requireCookieJar(ctn)),
+					opt.Workers,
+				), nil
+			},
+		},
+		godi.Def{
+			Name: diVotesResolver,
+			Build: func(ctn *godi.Container) (obj any, err error) {
+				return resolver.NewVotesResolver(
+					requireLogger(ctn),
 					requireImdbDataLoader(ctn),
+					opt.ResolveWorkers,
 				), nil
 			},
 		},
@@ -107,13 +189,36 @@ func getBuilder(ctx context.Context, log *zap.Logger, opt Options) (*godi.Builde
 				return writer.NewIMDbCSVVotesWriter(requireLogger(ctn)), nil
 			},
 		},
+		godi.Def{
+			Name: diUnmatchedWriter,
+			Build: func(ctn *godi.Container) (obj any, err error) {
+				return writer.NewUnmatchedCSVVotesWriter(requireLogger(ctn)), nil
+			},
+		},
+		godi.Def{
+			Name: diReviewWriter,
+			Build: func(ctn *godi.Container) (obj any, err error) {
+				return writer.NewReviewCSVVotesWriter(requireLogger(ctn)), nil
+			},
+		},
+		godi.Def{
+			Name: diAuditWriter,
+			Build: func(ctn *godi.Container) (obj any, err error) {
+				return writer.NewJSONAuditWriter(requireLogger(ctn)), nil
+			},
+		},
 		godi.Def{
 			Name: diRunner,
 			Build: func(ctn *godi.Container) (obj any, err error) {
 				return &runner{
-					log:    requireLogger(ctn),
-					reader: requireReader(ctn),
-					writer: requireWriter(ctn),
+					log:      requireLogger(ctn),
+					reader:   requireReader(ctn),
+					resolver: requireResolver(ctn),
+					writer:   requireWriter(ctn),
+
+					unmatchedWriter: requireUnmatchedWriter(ctn),
+					reviewWriter:    requireReviewWriter(ctn),
+					auditWriter:     requireAuditWriter(ctn),
 				}, nil
 			},
 		},
@@ -125,10 +230,92 @@ func getBuilder(ctx context.Context, log *zap.Logger, opt Options) (*godi.Builde
 	return builder, nil
 }
 
+func buildImdbResolver(
+	ctn *godi.Container,
+	opt Options,
+	name string,
+	overrides imdb.Overrides,
+) (imdb.DataLoader, error) {
+	logger := requireLogger(ctn)
+
+	switch strings.TrimSpace(name) {
+	case IMDbResolverOverrides:
+		return overrides, nil
+	case IMDbResolverCache:
+		return imdb.NewCacheDataLoader(requireImdbCache(ctn), opt.IMDbRetryMisses), nil
+	case IMDbResolverKinopoisk:
+		return imdb.NewKinopoiskPageDataLoader(
+			logger,
+			newDownloader(ctn, opt, kinopoisk.TimeoutFilm, requireCookieJar(ctn)),
+		), nil
+	case IMDbResolverWikidata:
+		return imdb.NewWikidataDataLoader(
+			logger,
+			wrapDownloader(ctn, opt, downloader.NewStdDownloaderWithUserAgent(
+				logger, imdb.TimeoutWikidata, opt.ProxyURL, imdb.WikidataUserAgent,
+			)),
+			opt.WikidataEndpoint,
+		), nil
+	case IMDbResolverDataset:
+		dataset, err := ctn.SafeGet(diImdbDataset)
+		if err != nil {
+			return nil, err
+		}
+
+		return imdb.NewDatasetDataLoader(logger, dataset.(*imdb.Dataset), opt.IMDbMinConfidence), nil
+	case IMDbResolverFind:
+		return imdb.NewFindDataLoader(
+			logger,
+			newDownloader(ctn, opt, imdb.TimeoutFind, nil),
+			opt.IMDbMinConfidence,
+		), nil
+	case IMDbResolverSuggest:
+		return imdb.NewSuggestDataLoader(
+			logger,
+			newDownloader(ctn, opt, imdb.TimeoutSuggest, nil),
+			opt.IMDbSuggestURL,
+			opt.IMDbMinConfidence,
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown IMDb resolver '%s'", name)
+	}
+}
+
+// newDownloader creates the Downloader with the timeout of each request,
+// limiting the requests rates by the host and retrying the transient failures.
+// The jar is set for the kinopoisk downloaders only, nil for the others.
+func newDownloader(
+	ctn *godi.Container,
+	opt Options,
+	timeout time.Duration,
+	jar http.CookieJar,
+) downloader.Downloader {
+	return wrapDownloader(ctn, opt, downloader.NewStdDownloaderWithCookies(requireLogger(ctn), timeout, opt.ProxyURL, jar))
+}
+
+// wrapDownloader adds the rate limiting and retries to the std Downloader.
+func wrapDownloader(ctn *godi.Container, opt Options, std downloader.Downloader) downloader.Downloader {
+	logger := requireLogger(ctn)
+
+	return downloader.NewRetryDownloader(
+		logger,
+		downloader.NewRateLimitDownloader(logger, std, requireRateLimiter(ctn)),
+		opt.Retry,
+	)
+}
+
 func requireLogger(ctn *godi.Container) *zap.Logger {
 	return ctn.Get(diLogger).(*zap.Logger)
 }
 
+func requireRateLimiter(ctn *godi.Container) *downloader.RateLimiter {
+	return ctn.Get(diRateLimiter).(*downloader.RateLimiter)
+}
+
+func requireCookieJar(ctn *godi.Container) http.CookieJar {
+	return ctn.Get(diCookieJar).(http.CookieJar)
+}
+
 func requireImdbCache(ctn *godi.Container) imdb.Cache {
 	return ctn.Get(diImdbCache).(imdb.Cache)
 }
@@ -141,10 +328,26 @@ func requireReader(ctn *godi.Container) reader.VotesReader {
 	return ctn.Get(diVotesReader).(reader.VotesReader)
 }
 
+func requireResolver(ctn *godi.Container) resolver.VotesResolver {
+	return ctn.Get(diVotesResolver).(resolver.VotesResolver)
+}
+
 func requireWriter(ctn *godi.Container) writer.VotesWriter {
 	return ctn.Get(diVotesWriter).(writer.VotesWriter)
 }
 
+func requireUnmatchedWriter(ctn *godi.Container) writer.UnmatchedVotesWriter {
+	return ctn.Get(diUnmatchedWriter).(writer.UnmatchedVotesWriter)
+}
+
+func requireReviewWriter(ctn *godi.Container) writer.ReviewVotesWriter {
+	return ctn.Get(diReviewWriter).(writer.ReviewVotesWriter)
+}
+
+func requireAuditWriter(ctn *godi.Container) writer.AuditWriter {
+	return ctn.Get(diAuditWriter).(writer.AuditWriter)
+}
+
 func requireRunner(ctn *godi.Container) *runner {
 	return ctn.Get(diRunner).(*runner)
 }
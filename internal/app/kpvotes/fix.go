@@ -0,0 +1,67 @@
+package kpvotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/fixer"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"go.uber.org/zap"
+)
+
+// Fix applies the IMDb IDs filled in the report to the cache and overrides files.
+func Fix(ctx context.Context, log *zap.Logger, opt FixOptions) error {
+	if opt.IMDbCacheFile == "" && opt.IMDbOverridesFile == "" {
+		return errors.New("at least one of the imdb cache or overrides file is required")
+	}
+
+	log = log.With(zap.String("who", "fix"), zap.String("report", opt.ReportFile))
+
+	// Without the overrides file the corrections are stored to the cache only.
+	var overrides imdb.Overrides
+
+	if opt.IMDbOverridesFile != "" {
+		loaded, err := imdb.LoadOverrides(log, opt.IMDbOverridesFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		overrides = make(imdb.Overrides)
+
+		if loaded != nil {
+			overrides = loaded
+		}
+	}
+
+	// Misses are kept as is, the corrected ones are replaced by the stored IDs.
+	storage := opt.CacheStorageOptions
+	storage.IMDbMissTTL = 0
+
+	cache, err := openCache(ctx, log, storage)
+	if err != nil {
+		return err
+	}
+
+	applied, err := fixer.NewFixer(log, cache, overrides).Fix(ctx, opt.ReportFile)
+	if err != nil {
+		_ = closeCache(ctx, cache, "", false)
+
+		return fmt.Errorf("failed to apply corrections: %w", err)
+	}
+
+	if err := closeCache(ctx, cache, storage.GetIMDbCacheSaveFile(), false); err != nil {
+		return err
+	}
+
+	if opt.IMDbOverridesFile != "" {
+		if err := overrides.Save(opt.IMDbOverridesFile); err != nil {
+			return err
+		}
+	}
+
+	log.Info(fmt.Sprintf("%d correction(s) applied", applied))
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package fixer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"go.uber.org/zap"
+)
+
+const correctionSkip = "skip"
+
+const (
+	colIMDbID        = "IMDb ID"
+	colKinopoiskURL  = "Kinopoisk URL"
+	colTitle         = "Title"
+	colOriginalTitle = "Original Title"
+	colYear          = "Year"
+)
+
+// ErrNoOverrides is returned if the report has the skip corrections, but there is no overrides file to save them to.
+var ErrNoOverrides = errors.New("overrides file is required to apply the skip corrections")
+
+// NewFixer creates the Fixer; the overrides are nil if there is no overrides file,
+// then the IMDb IDs are stored to the cache only and the skip corrections are rejected.
+func NewFixer(log *zap.Logger, cache imdb.Cache, overrides imdb.Overrides) Fixer {
+	return &fixer{
+		log:       log.With(zap.String("who", "fixer")),
+		cache:     cache,
+		overrides: overrides,
+	}
+}
+
+// Fixer applies the IMDb IDs filled in the report by user to the cache and overrides.
+type Fixer interface {
+	// Fix reads the report and applies its corrections, returns the number of applied corrections.
+	Fix(ctx context.Context, reportPath string) (int, error)
+}
+
+// Correction is a manually set IMDb ID for the vote.
+type Correction struct {
+	Vote   kinopoisk.Vote
+	ImdbID imdb.TitleID
+	Skip   bool
+}
+
+type fixer struct {
+	log       *zap.Logger
+	cache     imdb.Cache
+	overrides imdb.Overrides
+}
+
+func (f *fixer) Fix(ctx context.Context, reportPath string) (int, error) {
+	corrections, err := ReadCorrections(reportPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if f.overrides == nil {
+		for _, corr := range corrections {
+			if corr.Skip {
+				return 0, fmt.Errorf("skip correction for %s: %w", corr.Vote.MovieURL, ErrNoOverrides)
+			}
+		}
+	}
+
+	applied := 0
+
+	for _, corr := range corrections {
+		if err := ctx.Err(); err != nil {
+			return applied, err
+		}
+
+		log := f.log.With(zap.String("movie_url", corr.Vote.MovieURL))
+
+		filmID, ok := imdb.ParseKinopoiskFilmID(corr.Vote.MovieURL)
+		if !ok {
+			log.Warn("No kinopoisk film ID in URL, skipped")
+
+			continue
+		}
+
+		if corr.Skip {
+			f.overrides.Skip(filmID)
+			applied++
+
+			continue
+		}
+
+		if f.overrides != nil {
+			f.overrides.Set(filmID, corr.ImdbID)
+		}
+
+		entry := imdb.NewCacheEntry(corr.Vote.ToIMDbQuery(), corr.ImdbID, imdb.Match{Source: imdb.SourceOverride, Confidence: 1})
+
+		if err := f.cache.Store(ctx, entry); err != nil {
+			log.Warn("Failed to store IMDb ID in cache: " + err.Error())
+		}
+
+		applied++
+	}
+
+	return applied, nil
+}
+
+// ReadCorrections reads the rows with the filled IMDb ID column from the unmatched or review report CSV file.
+func ReadCorrections(sourcePath string) ([]Correction, error) {
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open report file %s: %w", sourcePath, err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report header from %s: %w", sourcePath, err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+
+	for _, required := range []string{colIMDbID, colKinopoiskURL} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("no '%s' column in report %s", required, sourcePath)
+		}
+	}
+
+	get := func(row []string, col string) string {
+		i, ok := cols[col]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return strings.TrimSpace(row[i])
+	}
+
+	corrections := make([]Correction, 0)
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report %s: %w", sourcePath, err)
+		}
+
+		val := get(row, colIMDbID)
+		if val == "" {
+			continue
+		}
+
+		corr := Correction{
+			Vote: kinopoisk.Vote{
+				MovieURL:          get(row, colKinopoiskURL),
+				MovieNameRu:       get(row, colTitle),
+				MovieNameOriginal: get(row, colOriginalTitle),
+				MovieYear:         get(row, colYear),
+			},
+		}
+
+		if strings.EqualFold(val, correctionSkip) {
+			corr.Skip = true
+		} else {
+			corr.ImdbID = imdb.TitleID(val)
+
+			if !corr.ImdbID.IsValid() {
+				return nil, fmt.Errorf("invalid IMDb ID '%s' for %s in report %s", val, corr.Vote.MovieURL, sourcePath)
+			}
+		}
+
+		corrections = append(corrections, corr)
+	}
+
+	return corrections, nil
+}
@@ -0,0 +1,76 @@
+package fixer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/fixer"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixer_Fix(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	overrides := make(imdb.Overrides)
+
+	applied, err := fixer.NewFixer(log, cache, overrides).Fix(ctx, "./testdata/report.csv")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	entry, ok, err := cache.Get(ctx, imdb.Query{
+		Title:        "Anatomie d'une chute (2023)",
+		KinopoiskURL: "https://www.kinopoisk.ru/film/4910679/",
+	})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt17009710"), entry.ID)
+	assert.Equal(t, "4910679", entry.KinopoiskID)
+
+	id, ok, err := overrides.Get(imdb.Query{KinopoiskURL: "https://www.kinopoisk.ru/film/4910679/"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+
+	_, ok, err = overrides.Get(imdb.Query{KinopoiskURL: "https://www.kinopoisk.ru/film/474953/"})
+	assert.True(t, ok)
+	assert.True(t, errors.Is(err, imdb.ErrSkipped))
+
+	_, ok, _ = overrides.Get(imdb.Query{KinopoiskURL: "https://www.kinopoisk.ru/film/4291715/"})
+	assert.False(t, ok)
+}
+
+func TestReadCorrections_FromReviewReport(t *testing.T) {
+	corrections, err := fixer.ReadCorrections("./testdata/review.csv")
+
+	require.NoError(t, err)
+	require.Len(t, corrections, 2)
+
+	assert.Equal(t, "https://www.kinopoisk.ru/film/4910679/", corrections[0].Vote.MovieURL)
+	assert.Equal(t, imdb.TitleID("tt17009710"), corrections[0].ImdbID)
+	assert.True(t, corrections[1].Skip)
+}
+
+func TestFixer_Fix_WhenNoOverrides(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+
+	applied, err := fixer.NewFixer(log, cache, nil).Fix(ctx, "./testdata/report.csv")
+
+	assert.ErrorIs(t, err, fixer.ErrNoOverrides)
+	assert.Equal(t, 0, applied)
+
+	// Nothing is applied partially.
+	_, ok, err := cache.Get(ctx, imdb.Query{
+		Title:        "Anatomie d'une chute (2023)",
+		KinopoiskURL: "https://www.kinopoisk.ru/film/4910679/",
+	})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
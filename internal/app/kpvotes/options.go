@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
 )
 
@@ -13,15 +17,61 @@ const (
 	envProxyURL = envPrefix + "PROXY_URL"
 )
 
+const (
+	IMDbResolverOverrides = "overrides"
+	IMDbResolverCache     = "cache"
+	IMDbResolverFind      = "find"
+	IMDbResolverDataset   = "dataset"
+	IMDbResolverKinopoisk = "kinopoisk"
+	IMDbResolverWikidata  = "wikidata"
+	IMDbResolverSuggest   = "suggest"
+)
+
+const (
+	IMDbCacheBackendMemory = "memory"
+	IMDbCacheBackendBolt   = "bolt"
+)
+
+// DefaultReviewConfidence is a default confidence the matches below are written to the review report.
+const DefaultReviewConfidence = 0.75
+
+const (
+	OutputFormatTable = "table"
+	OutputFormatJSON  = "json"
+)
+
 type Options struct {
 	UserID   kinopoisk.UserID
 	ProxyURL *url.URL
 
-	TargetFile    string
-	IMDbCacheFile string
+	CacheStorageOptions
+
+	TargetFile        string
+	IMDbCacheJournal  bool
+	IMDbOverridesFile string
 
 	TargetChunkSize uint
 
+	IMDbMinConfidence float64
+	IMDbRetryMisses   bool
+	IMDbResolvers     []string
+	IMDbDatasetDir    string
+	IMDbSuggestURL    string
+	WikidataEndpoint  string
+
+	WriteAudit bool
+	// IMDbReviewConfidence is a confidence the matches below are written to the review report, 0 to disable.
+	IMDbReviewConfidence float64
+
+	Workers        uint
+	ResolveWorkers uint
+
+	Retry downloader.RetryOptions
+	// RateLimits are the max requests rates by the host, see downloader.ParseRateLimits.
+	RateLimits string
+	// CookiesFile is a Netscape or JSON cookies file with the kinopoisk session.
+	CookiesFile string
+
 	IsDebug bool
 }
 
@@ -37,3 +87,71 @@ func (o *Options) SetFromEnv() error {
 
 	return nil
 }
+
+// GetIMDbResolvers returns the names of the IMDb resolvers chain links in order.
+// If the overrides file is set, the overrides link is always put first, so the manual IDs win.
+func (o *Options) GetIMDbResolvers() []string {
+	if len(o.IMDbResolvers) == 0 {
+		return []string{IMDbResolverOverrides, IMDbResolverCache, IMDbResolverFind}
+	}
+
+	if o.IMDbOverridesFile == "" {
+		return o.IMDbResolvers
+	}
+
+	resolvers := make([]string, 0, len(o.IMDbResolvers)+1)
+	resolvers = append(resolvers, IMDbResolverOverrides)
+
+	for _, name := range o.IMDbResolvers {
+		if strings.TrimSpace(name) != IMDbResolverOverrides {
+			resolvers = append(resolvers, name)
+		}
+	}
+
+	return resolvers
+}
+
+// FixOptions are the options of the corrections applying.
+type FixOptions struct {
+	CacheStorageOptions
+
+	ReportFile        string
+	IMDbOverridesFile string
+}
+
+// CacheOptions are the options of the cache management commands.
+type CacheOptions struct {
+	CacheStorageOptions
+
+	OutputFormat string
+}
+
+// CacheStorageOptions are the options of the IMDb cache storage.
+type CacheStorageOptions struct {
+	// IMDbCacheFile is a cache file, directory or glob pattern of the files to merge.
+	IMDbCacheFile string
+	// IMDbCacheSaveFile is a file to save the cache to if differs from the IMDbCacheFile.
+	IMDbCacheSaveFile string
+
+	IMDbCacheBackend   string
+	IMDbCacheConflicts string
+	IMDbMissTTL        time.Duration
+}
+
+// GetIMDbCacheSaveFile returns the file the cache is saved to: the IMDbCacheSaveFile if set,
+// the IMDbCacheFile if it's not a directory or pattern, empty otherwise.
+func (o CacheStorageOptions) GetIMDbCacheSaveFile() string {
+	if o.IMDbCacheSaveFile != "" {
+		return o.IMDbCacheSaveFile
+	}
+
+	if o.IMDbCacheFile == "" || imdb.IsCachePattern(o.IMDbCacheFile) {
+		return ""
+	}
+
+	if stat, err := os.Stat(o.IMDbCacheFile); err == nil && stat.IsDir() {
+		return ""
+	}
+
+	return o.IMDbCacheFile
+}
@@ -0,0 +1,28 @@
+package kpvotes_test
+
+import (
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions_GetIMDbResolvers(t *testing.T) {
+	tests := []struct {
+		Resolvers     []string
+		OverridesFile string
+		Expected      []string
+	}{
+		{nil, "", []string{"overrides", "cache", "find"}},
+		{[]string{"cache", "suggest"}, "", []string{"cache", "suggest"}},
+		{[]string{"cache", "suggest"}, "overrides.csv", []string{"overrides", "cache", "suggest"}},
+		{[]string{"cache", "overrides"}, "overrides.csv", []string{"overrides", "cache"}},
+		{[]string{"overrides", "cache"}, "overrides.csv", []string{"overrides", "cache"}},
+	}
+
+	for i, test := range tests {
+		opt := kpvotes.Options{IMDbResolvers: test.Resolvers, IMDbOverridesFile: test.OverridesFile}
+
+		assert.Equal(t, test.Expected, opt.GetIMDbResolvers(), i)
+	}
+}
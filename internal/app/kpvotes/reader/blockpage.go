@@ -0,0 +1,20 @@
+package reader
+
+import (
+	"errors"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk/blockpage"
+	"golang.org/x/net/html"
+)
+
+// ErrUnexpectedPage is returned if the page has no votes list and no "nothing found" message.
+var ErrUnexpectedPage = errors.New("no votes list on the kinopoisk page")
+
+// detectMissingList returns the error explaining why the votes list is missing on the page.
+func detectMissingList(doc *html.Node) error {
+	if err := blockpage.Detect(doc); err != nil {
+		return err
+	}
+
+	return ErrUnexpectedPage
+}
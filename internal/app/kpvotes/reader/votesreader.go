@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,38 +12,181 @@ import (
 
 	"github.com/antchfx/htmlquery"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
-	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk/blockpage"
 	"go.uber.org/zap"
 	"golang.org/x/net/html"
 )
 
+const votesPerPage = 200
+
+const nothingFoundText = "Ни одной записи не найдено"
+
 var errNothingFound = errors.New("nothing found")
 
-func NewVotesReader(log *zap.Logger, downloader downloader.Downloader, imdbLoader imdb.DataLoader) VotesReader {
+var pagesFromToRx = regexp.MustCompile(`([0-9]+)\s*$`)
+
+func NewVotesReader(log *zap.Logger, downloader downloader.Downloader, workers uint) VotesReader {
+	if workers == 0 {
+		workers = 1
+	}
+
 	return &votesReader{
-		log:            log.With(zap.String("who", "votesReader")),
-		downloader:     downloader,
-		imdbDataLoader: imdbLoader,
+		log:        log.With(zap.String("who", "votesReader")),
+		downloader: downloader,
+		workers:    workers,
 	}
 }
 
 type VotesReader interface {
-	ReadVotes(ctx context.Context, userID kinopoisk.UserID) (kinopoisk.Votes, error)
+	// ReadVotes reads user's votes and sends them to the out channel in the pages order.
+	// The out channel is not closed by the reader.
+	ReadVotes(ctx context.Context, userID kinopoisk.UserID, out chan<- kinopoisk.Vote) error
 }
 
 type votesReader struct {
-	log            *zap.Logger
-	downloader     downloader.Downloader
-	imdbDataLoader imdb.DataLoader
+	log        *zap.Logger
+	downloader downloader.Downloader
+	workers    uint
+}
+
+type pageResult struct {
+	votes kinopoisk.Votes
+	err   error
 }
 
-func (r *votesReader) ReadVotes(ctx context.Context, userID kinopoisk.UserID) (kinopoisk.Votes, error) {
+func (r *votesReader) ReadVotes(ctx context.Context, userID kinopoisk.UserID, out chan<- kinopoisk.Vote) error {
 	log := r.log.With(zap.String("uid", userID.String()))
-	pageN := uint16(1)
-	votes := make(kinopoisk.Votes, 0)
 
-	// TODO: download pages in several goroutines
+	doc, err := r.downloadPage(ctx, log, userID, 1)
+	if err != nil {
+		return fmt.Errorf("failed to read votes page #1 for user %s: %w", userID.String(), err)
+	}
+
+	votes, err := r.parseHTML(ctx, log, doc, 1)
+	if errors.Is(err, errNothingFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read votes page #1 for user %s: %w", userID.String(), err)
+	}
+
+	if err := sendVotes(ctx, votes, out); err != nil {
+		return err
+	}
+
+	pagesCount, ok := parsePagesCount(doc)
+	if !ok {
+		log.Warn("No paginator found, reading pages sequentially")
+
+		return r.readPagesSequentially(ctx, log, userID, out)
+	}
+
+	log.Debug(fmt.Sprintf("Found %d page(s)", pagesCount))
+
+	if pagesCount <= 1 {
+		return nil
+	}
+
+	return r.readPagesConcurrently(ctx, log, userID, 2, pagesCount, out)
+}
+
+// readPagesConcurrently reads pages from `from` to `to` inclusively
+// using not more than r.workers goroutines.
+// Votes are sent to the out channel ordered by the page number, errors of all failed pages are joined.
+// If kinopoisk serves the captcha or login page, the pages in progress are canceled and the rest are not requested.
+func (r *votesReader) readPagesConcurrently(
+	ctx context.Context,
+	log *zap.Logger,
+	userID kinopoisk.UserID,
+	from uint16,
+	to uint16,
+	out chan<- kinopoisk.Vote,
+) error {
+	count := int(to-from) + 1
+	results := make([]chan pageResult, count)
+
+	for i := range results {
+		results[i] = make(chan pageResult, 1)
+	}
+
+	pagesCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	go func() {
+		sem := make(chan struct{}, r.workers)
+
+		for i := 0; i < count; i++ {
+			i := i
+			pageN := from + uint16(i)
+
+			select {
+			case <-pagesCtx.Done():
+			case sem <- struct{}{}:
+			}
+
+			// The slot may be taken right after the cancel, the page must not be requested anyway.
+			if err := pagesCtx.Err(); err != nil {
+				results[i] <- pageResult{err: err}
+
+				continue
+			}
+
+			go func() {
+				defer func() {
+					<-sem
+				}()
+
+				pageVotes, err := r.readPage(pagesCtx, log, userID, pageN)
+				if err != nil && !errors.Is(err, errNothingFound) {
+					err = fmt.Errorf("failed to read votes page #%d for user %s: %w", pageN, userID.String(), err)
+				} else {
+					err = nil
+				}
+
+				if blockpage.IsBlocked(err) {
+					cancel(err)
+				}
+
+				results[i] <- pageResult{votes: pageVotes, err: err}
+			}()
+		}
+	}()
+
+	errs := make([]error, 0)
+
+	for i := 0; i < count; i++ {
+		res := <-results[i]
+
+		if res.err != nil {
+			// The rest of the pages are canceled, the blocking page error is the reason.
+			if cause := context.Cause(pagesCtx); blockpage.IsBlocked(cause) {
+				return errors.Join(append(errs, cause)...)
+			}
+
+			errs = append(errs, res.err)
+
+			continue
+		}
+
+		if err := sendVotes(ctx, res.votes, out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// readPagesSequentially reads pages one by one starting from the second one until the empty page is found.
+func (r *votesReader) readPagesSequentially(
+	ctx context.Context,
+	log *zap.Logger,
+	userID kinopoisk.UserID,
+	out chan<- kinopoisk.Vote,
+) error {
+	pageN := uint16(2)
+
 	for {
 		pageVotes, err := r.readPage(ctx, log, userID, pageN)
 
@@ -52,15 +195,17 @@ func (r *votesReader) ReadVotes(ctx context.Context, userID kinopoisk.UserID) (k
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to read votes page #%d for user %s: %w", pageN, userID.String(), err)
+			return fmt.Errorf("failed to read votes page #%d for user %s: %w", pageN, userID.String(), err)
 		}
 
-		votes = append(votes, pageVotes...)
+		if err := sendVotes(ctx, pageVotes, out); err != nil {
+			return err
+		}
 
 		pageN++
 	}
 
-	return votes, nil
+	return nil
 }
 
 func (r *votesReader) readPage(
@@ -69,15 +214,29 @@ func (r *votesReader) readPage(
 	userID kinopoisk.UserID,
 	pageN uint16,
 ) (kinopoisk.Votes, error) {
+	doc, err := r.downloadPage(ctx, log, userID, pageN)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.parseHTML(ctx, log.With(zap.Uint16("page", pageN)), doc, pageN)
+}
+
+func (r *votesReader) downloadPage(
+	ctx context.Context,
+	log *zap.Logger,
+	userID kinopoisk.UserID,
+	pageN uint16,
+) (*html.Node, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	log = r.log.With(zap.Uint16("page", pageN))
+	log = log.With(zap.Uint16("page", pageN))
 
 	log.Debug("Reading votes")
 
-	pageURL := userID.ToURL() + "/votes/list/vs/vote/perpage/200/page/" + fmt.Sprintf("%d", pageN)
+	pageURL := userID.ToURL() + "/votes/list/vs/vote/perpage/" + fmt.Sprintf("%d/page/%d", votesPerPage, pageN)
 
 	body, err := r.downloader.Download(ctx, pageURL)
 	if err != nil {
@@ -90,65 +249,73 @@ func (r *votesReader) readPage(
 		_ = body.Close()
 	}()
 
-	return r.parseHTML(ctx, log, body, pageN)
+	doc, err := htmlquery.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse body: %w", err)
+	}
+
+	return doc, nil
 }
 
 func (r *votesReader) parseHTML(
 	ctx context.Context,
 	log *zap.Logger,
-	body io.Reader,
+	doc *html.Node,
 	pageN uint16,
 ) (kinopoisk.Votes, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	doc, err := htmlquery.Parse(body)
+	errBox, _ := htmlquery.Query(doc, `//form[@id="f_filtr"]`)
+	if errBox != nil {
+		if strings.Contains(htmlquery.InnerText(errBox), nothingFoundText) {
+			return nil, errNothingFound
+		}
+	}
+
+	listNode, err := htmlquery.Query(doc, `//div[@class="profileFilmsList"]`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse body: %w", err)
+		return nil, fmt.Errorf("failed to parse votes list: %w", err)
 	}
 
-	errBox, _ := htmlquery.Query(doc, `//form[@id="f_filtr"]`)
-	if errBox != nil {
-		if strings.Contains(htmlquery.InnerText(errBox), "Ни одной записи не найдено") {
+	// Not the votes page is served, its absent votes must not be taken for the end of the list.
+	if listNode == nil {
+		if strings.Contains(htmlquery.InnerText(doc), nothingFoundText) {
 			return nil, errNothingFound
 		}
+
+		return nil, detectMissingList(doc)
 	}
 
-	itemNodes, err := htmlquery.QueryAll(doc, `//div[@class="profileFilmsList"]/div[`+xpathClass("item")+`]`)
+	itemNodes, err := htmlquery.QueryAll(listNode, `div[`+xpathClass("item")+`]`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse items: %w", err)
 	}
 
 	votes := make(kinopoisk.Votes, 0, len(itemNodes))
 
-	for i, node := range itemNodes {
+	for _, node := range itemNodes {
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
 
 		vote := r.parseItemNode(log, node)
+		if vote == nil {
+			continue
+		}
 
-		if vote != nil {
-			imdbID, err := r.imdbDataLoader.GetIDByTitle(ctx, vote.GetOriginalTitle())
-			if err != nil {
-				log.Debug("failed to get IMDb ID for " + vote.GetOriginalTitle() + ": " + err.Error())
-
-				continue
-			}
-
-			vote.ImdbID = imdbID
-
-			if err := votes.Add(*vote); err != nil {
-				log.Debug(err.Error())
+		if err := vote.Validate(); err != nil {
+			log.Debug(err.Error())
 
-				continue
-			}
+			continue
 		}
 
-		log.Info(fmt.Sprintf("[read][page#%d][%03d/%03d] done", pageN, i+1, len(itemNodes)))
+		votes = append(votes, *vote)
 	}
 
+	log.Info(fmt.Sprintf("[read][page#%d] %d vote(s) read", pageN, len(votes)))
+
 	return votes, nil
 }
 
@@ -196,15 +363,41 @@ func (r *votesReader) parseItemNode(log *zap.Logger, node *html.Node) *kinopoisk
 	return &vote
 }
 
-func (r *votesReader) processParsedVote(ctx context.Context, vote *kinopoisk.Vote) {
-	imdbID, err := r.imdbDataLoader.GetIDByTitle(ctx, vote.GetOriginalTitle())
+// parsePagesCount returns the pages count from the paginator's "1—200 из 552" text.
+func parsePagesCount(doc *html.Node) (uint16, bool) {
+	node, err := htmlquery.Query(doc, `//div[@class="pagesFromTo"]`)
+	if err != nil || node == nil {
+		return 0, false
+	}
+
+	match := pagesFromToRx.FindStringSubmatch(htmlquery.InnerText(node))
+	if match == nil {
+		return 0, false
+	}
+
+	total, err := strconv.ParseUint(match[1], 10, 64)
 	if err != nil {
-		r.log.Debug("failed to get IMDb ID for " + vote.GetOriginalTitle() + ": " + err.Error())
+		return 0, false
+	}
+
+	pages := (total + votesPerPage - 1) / votesPerPage
+	if pages > math.MaxUint16 {
+		return 0, false
+	}
 
-		return
+	return uint16(pages), true
+}
+
+func sendVotes(ctx context.Context, votes kinopoisk.Votes, out chan<- kinopoisk.Vote) error {
+	for _, vote := range votes {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- vote:
+		}
 	}
 
-	vote.ImdbID = imdbID
+	return nil
 }
 
 func xpathClass(class string) string {
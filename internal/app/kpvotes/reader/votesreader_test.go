@@ -1,14 +1,23 @@
 package reader_test
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/reader"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
-	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk/blockpage"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVotesReader_ReadVotes(t *testing.T) {
@@ -16,13 +25,203 @@ func TestVotesReader_ReadVotes(t *testing.T) {
 	dwn := downloader.NewDownloaderFileMock(map[string]string{
 		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/1": "./testdata/votes_page1.html",
 		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/2": "./testdata/votes_page2.html",
-		"https://www.imdb.com/find/?q=Anatomie+d%27une+chute+%282023%29&s=all":         "./testdata/imdb_1.html",
+		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/3": "./testdata/votes_page2.html",
 	})
-	imdbDL := imdb.NewDataLoader(log, dwn)
-	rd := reader.NewVotesReader(log, dwn, imdbDL)
+	rd := reader.NewVotesReader(log, dwn, 2)
 
-	votes, err := rd.ReadVotes(context.Background(), 33666291)
+	votes, err := readVotes(rd)
 
 	assert.NoError(t, err)
-	assert.Len(t, votes, 1)
+	assert.Len(t, votes, 200)
+	assert.Equal(t, "Anatomie d'une chute (2023)", votes[0].GetOriginalTitle())
+}
+
+func TestVotesReader_ReadVotes_KeepsPagesOrder(t *testing.T) {
+	const pageURL = "https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/"
+
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := &delayedDownloader{
+		Downloader: downloader.NewDownloaderFileMock(map[string]string{
+			pageURL + "1": "./testdata/votes_ordered_page1.html",
+			pageURL + "2": "./testdata/votes_ordered_page2.html",
+			pageURL + "3": "./testdata/votes_ordered_page3.html",
+		}),
+		// The page 2 is downloaded after the page 3, the votes must be sent in the pages order anyway.
+		delays: map[string]time.Duration{pageURL + "2": 100 * time.Millisecond},
+	}
+	rd := reader.NewVotesReader(log, dwn, 3)
+
+	votes, err := readVotes(rd)
+
+	require.NoError(t, err)
+
+	urls := make([]string, 0, len(votes))
+	for _, vote := range votes {
+		urls = append(urls, vote.MovieURL)
+	}
+
+	assert.Equal(t, []string{
+		"/film/4910679/",
+		"/film/474953/",
+		"/film/4291715/",
+		"/film/722995/",
+		"/film/103733/",
+		"/series/784529/",
+		"/film/4926291/",
+		"/film/1015471/",
+		"/film/1238292/",
+	}, urls)
+}
+
+func TestVotesReader_ReadVotes_WhenPageFailed(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := downloader.NewDownloaderFileMock(map[string]string{
+		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/1": "./testdata/votes_page1.html",
+		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/2": "./testdata/votes_page2.html",
+	})
+	rd := reader.NewVotesReader(log, dwn, 2)
+
+	_, err := readVotes(rd)
+
+	assert.ErrorContains(t, err, "page #3")
+}
+
+func TestVotesReader_ReadVotes_WhenBlocked(t *testing.T) {
+	const pageURL = "https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/"
+
+	tests := []struct {
+		Sources  map[string]string
+		Expected error
+	}{
+		{
+			Sources:  map[string]string{pageURL + "1": "./testdata/captcha.html"},
+			Expected: blockpage.ErrCaptcha,
+		},
+		{
+			Sources:  map[string]string{pageURL + "1": "./testdata/login.html"},
+			Expected: blockpage.ErrLoginRequired,
+		},
+		{
+			Sources: map[string]string{
+				pageURL + "1": "./testdata/votes_page1.html",
+				pageURL + "2": "./testdata/captcha.html",
+				pageURL + "3": "./testdata/votes_page2.html",
+			},
+			Expected: blockpage.ErrCaptcha,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			log := logger.NewDefaultConsoleLogger(true)
+			rd := reader.NewVotesReader(log, downloader.NewDownloaderFileMock(test.Sources), 2)
+
+			_, err := readVotes(rd)
+
+			assert.ErrorIs(t, err, test.Expected)
+		})
+	}
+}
+
+func TestVotesReader_ReadVotes_WhenBlockedStopsReading(t *testing.T) {
+	const pageURL = "https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/"
+
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := &recordingDownloader{
+		Downloader: downloader.NewDownloaderFileMock(map[string]string{
+			pageURL + "1": "./testdata/votes_page1.html",
+			pageURL + "2": "./testdata/captcha.html",
+			pageURL + "3": "./testdata/votes_page2.html",
+		}),
+	}
+
+	_, err := readVotes(reader.NewVotesReader(log, dwn, 1))
+
+	assert.ErrorIs(t, err, blockpage.ErrCaptcha)
+	assert.NotErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{pageURL + "1", pageURL + "2"}, dwn.requested)
+}
+
+func TestVotesReader_ReadVotes_WhenPageHasCaptchaWidget(t *testing.T) {
+	content, err := os.ReadFile("./testdata/votes_page1.html")
+	require.NoError(t, err)
+
+	// The votes page may load the captcha script for its own forms, the votes are still there.
+	content = bytes.Replace(
+		content,
+		[]byte("</head>"),
+		[]byte(`<script src="https://smartcaptcha.yandexcloud.net/captcha.js"></script></head>`),
+		1,
+	)
+
+	page1 := filepath.Join(t.TempDir(), "votes_page1.html")
+	require.NoError(t, os.WriteFile(page1, content, 0644))
+
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := downloader.NewDownloaderFileMock(map[string]string{
+		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/1": page1,
+		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/2": "./testdata/votes_page2.html",
+		"https://www.kinopoisk.ru/user/33666291/votes/list/vs/vote/perpage/200/page/3": "./testdata/votes_page2.html",
+	})
+
+	votes, err := readVotes(reader.NewVotesReader(log, dwn, 2))
+
+	assert.NoError(t, err)
+	assert.Len(t, votes, 200)
+}
+
+func readVotes(rd reader.VotesReader) (kinopoisk.Votes, error) {
+	out := make(chan kinopoisk.Vote)
+	votes := make(kinopoisk.Votes, 0)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for vote := range out {
+			votes = append(votes, vote)
+		}
+	}()
+
+	err := rd.ReadVotes(context.Background(), 33666291, out)
+
+	close(out)
+	<-done
+
+	return votes, err
+}
+
+// delayedDownloader delays the downloads of the URLs to change the pages completion order.
+type delayedDownloader struct {
+	downloader.Downloader
+
+	delays map[string]time.Duration
+}
+
+func (d *delayedDownloader) Download(ctx context.Context, pageURL string) (io.ReadCloser, error) {
+	if delay, ok := d.delays[pageURL]; ok {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return d.Downloader.Download(ctx, pageURL)
+}
+
+// recordingDownloader records the downloaded URLs.
+type recordingDownloader struct {
+	downloader.Downloader
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func (d *recordingDownloader) Download(ctx context.Context, pageURL string) (io.ReadCloser, error) {
+	d.mu.Lock()
+	d.requested = append(d.requested, pageURL)
+	d.mu.Unlock()
+
+	return d.Downloader.Download(ctx, pageURL)
 }
@@ -0,0 +1,171 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"go.uber.org/zap"
+)
+
+// ErrNotResolved is an error of the vote received, but not resolved before the context cancellation.
+var ErrNotResolved = errors.New("not resolved")
+
+func NewVotesResolver(log *zap.Logger, imdbLoader imdb.DataLoader, workers uint) VotesResolver {
+	if workers == 0 {
+		workers = 1
+	}
+
+	return &votesResolver{
+		log:            log.With(zap.String("who", "votesResolver")),
+		imdbDataLoader: imdbLoader,
+		workers:        workers,
+	}
+}
+
+// VotesResolver resolves the IMDb IDs of the votes.
+type VotesResolver interface {
+	// Resolve reads votes from the channel until it is closed, resolves their IMDb IDs
+	// and returns the resolved and unmatched votes in the order they were received.
+	// The votes skipped by the overrides are neither resolved nor unmatched.
+	// If the context is canceled, the votes received so far are returned with the error,
+	// the not resolved ones are unmatched.
+	Resolve(
+		ctx context.Context,
+		votes <-chan kinopoisk.Vote,
+	) (resolved kinopoisk.Votes, unmatched kinopoisk.UnmatchedVotes, err error)
+}
+
+type votesResolver struct {
+	log            *zap.Logger
+	imdbDataLoader imdb.DataLoader
+	workers        uint
+}
+
+type resolveJob struct {
+	n    int
+	vote kinopoisk.Vote
+}
+
+func (r *votesResolver) Resolve(
+	ctx context.Context,
+	votes <-chan kinopoisk.Vote,
+) (resolved kinopoisk.Votes, unmatched kinopoisk.UnmatchedVotes, err error) {
+	jobs := make(chan resolveJob)
+	results := make([]kinopoisk.UnmatchedVote, 0)
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for i := uint(0); i < r.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				vote := job.vote
+
+				err := r.resolveVote(ctx, &vote)
+				if err != nil {
+					r.log.Debug(err.Error())
+				}
+
+				mu.Lock()
+				results[job.n] = kinopoisk.UnmatchedVote{Vote: vote, Err: err}
+				mu.Unlock()
+
+				r.log.Info(fmt.Sprintf("[resolve][%03d] done", job.n+1))
+			}
+		}()
+	}
+
+	err = r.dispatch(ctx, votes, jobs, func(vote kinopoisk.Vote) {
+		mu.Lock()
+		results = append(results, kinopoisk.UnmatchedVote{Vote: vote, Err: ErrNotResolved})
+		mu.Unlock()
+	})
+
+	close(jobs)
+	wg.Wait()
+
+	if err != nil && ctx.Err() == nil {
+		return nil, nil, err
+	}
+
+	resolved = make(kinopoisk.Votes, 0, len(results))
+	unmatched = make(kinopoisk.UnmatchedVotes, 0)
+	skipped := 0
+
+	for _, res := range results {
+		if errors.Is(res.Err, imdb.ErrSkipped) {
+			skipped++
+
+			continue
+		}
+
+		if res.Err == nil {
+			res.Err = resolved.Add(res.Vote)
+		}
+
+		if res.Err != nil {
+			unmatched = append(unmatched, res)
+		}
+	}
+
+	if skipped > 0 {
+		r.log.Info(fmt.Sprintf("%d vote(s) skipped by the overrides", skipped))
+	}
+
+	return resolved, unmatched, err
+}
+
+// dispatch numbers the incoming votes and passes them to the workers.
+// The onReceive callback is called before the job is sent.
+func (r *votesResolver) dispatch(
+	ctx context.Context,
+	votes <-chan kinopoisk.Vote,
+	jobs chan<- resolveJob,
+	onReceive func(vote kinopoisk.Vote),
+) error {
+	n := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case vote, ok := <-votes:
+			if !ok {
+				return nil
+			}
+
+			onReceive(vote)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case jobs <- resolveJob{n: n, vote: vote}:
+			}
+
+			n++
+		}
+	}
+}
+
+func (r *votesResolver) resolveVote(ctx context.Context, vote *kinopoisk.Vote) error {
+	query := vote.ToIMDbQuery()
+
+	id, match, err := r.imdbDataLoader.GetID(ctx, query)
+
+	vote.ImdbMatch = match
+
+	if err != nil {
+		return fmt.Errorf("failed to get IMDb ID for %s: %w", query.Title, err)
+	}
+
+	vote.ImdbID = id
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/resolver"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVotesResolver_Resolve(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := downloader.NewDownloaderFileMock(map[string]string{
+		"https://www.imdb.com/find/?q=Anatomie+d%27une+chute+%282023%29&s=all": "./testdata/imdb_1.html",
+	})
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	imdbDL := imdb.NewChainDataLoader(
+		log,
+		cache,
+		imdb.NewCacheDataLoader(cache, false),
+		// The localized IMDb title of the found film is accepted with the lower confidence only.
+		imdb.NewFindDataLoader(log, dwn, 0.4),
+	)
+	rs := resolver.NewVotesResolver(log, imdbDL, 4)
+
+	votes := make(chan kinopoisk.Vote, 3)
+	votes <- kinopoisk.Vote{
+		MovieURL:          "/film/1/",
+		MovieNameOriginal: "Anatomie d'une chute",
+		MovieYear:         "2023",
+		Rate:              8,
+	}
+	votes <- kinopoisk.Vote{
+		MovieURL:    "/film/2/",
+		MovieNameRu: "Неизвестный фильм",
+		MovieYear:   "2020",
+		Rate:        5,
+	}
+	votes <- kinopoisk.Vote{
+		MovieURL:          "/film/3/",
+		MovieNameOriginal: "Anatomie d'une chute",
+		MovieYear:         "2023",
+		Rate:              9,
+	}
+	close(votes)
+
+	resolved, unmatched, err := rs.Resolve(context.Background(), votes)
+
+	require.NoError(t, err)
+	require.Len(t, resolved, 2)
+	require.Len(t, unmatched, 1)
+
+	assert.Equal(t, "/film/1/", resolved[0].MovieURL)
+	assert.Equal(t, "/film/3/", resolved[1].MovieURL)
+	assert.Equal(t, resolved[0].ImdbID, resolved[1].ImdbID)
+	assert.Equal(t, imdb.TitleID("tt17009710"), resolved[0].ImdbID)
+	assert.Contains(t, []imdb.Source{imdb.SourceFind, imdb.SourceCache}, resolved[0].ImdbMatch.Source)
+	assert.Equal(t, "/film/2/", unmatched[0].MovieURL)
+	assert.Error(t, unmatched[0].Err)
+}
+
+func TestVotesResolver_Resolve_Canceled(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.Store(context.Background(), imdb.CacheEntry{KinopoiskID: "1", ID: "tt17009710"}))
+
+	rs := resolver.NewVotesResolver(log, imdb.NewCacheDataLoader(cache, false), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The channel is never closed, the resolving is stopped by the cancellation only.
+	votes := make(chan kinopoisk.Vote, 2)
+	votes <- kinopoisk.Vote{MovieURL: "/film/1/", MovieNameOriginal: "Anatomie d'une chute", MovieYear: "2023", Rate: 8}
+	votes <- kinopoisk.Vote{MovieURL: "/film/2/", MovieNameOriginal: "Unknown", MovieYear: "2020", Rate: 5}
+
+	go func() {
+		for len(votes) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	resolved, unmatched, err := rs.Resolve(ctx, votes)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, resolved, 1)
+	require.Len(t, unmatched, 1)
+
+	assert.Equal(t, imdb.TitleID("tt17009710"), resolved[0].ImdbID)
+	assert.Equal(t, "/film/2/", unmatched[0].MovieURL)
+}
+
+func TestVotesResolver_Resolve_Skipped(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	overrides := imdb.Overrides{"1": "tt17009710"}
+	overrides.Skip("2")
+
+	rs := resolver.NewVotesResolver(log, imdb.NewChainDataLoader(log, cache, overrides), 2)
+
+	votes := make(chan kinopoisk.Vote, 3)
+	votes <- kinopoisk.Vote{MovieURL: "/film/1/", MovieNameOriginal: "Anatomie d'une chute", MovieYear: "2023", Rate: 8}
+	votes <- kinopoisk.Vote{MovieURL: "/film/2/", MovieNameOriginal: "Skipped", MovieYear: "2020", Rate: 5}
+	votes <- kinopoisk.Vote{MovieURL: "/film/3/", MovieNameOriginal: "Unknown", MovieYear: "2020", Rate: 6}
+	close(votes)
+
+	resolved, unmatched, err := rs.Resolve(context.Background(), votes)
+
+	require.NoError(t, err)
+	require.Len(t, resolved, 1)
+	require.Len(t, unmatched, 1)
+
+	assert.Equal(t, "/film/1/", resolved[0].MovieURL)
+	assert.Equal(t, "/film/3/", unmatched[0].MovieURL)
+}
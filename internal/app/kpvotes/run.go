@@ -2,13 +2,24 @@ package kpvotes
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/reader"
+	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/resolver"
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/writer"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk/blockpage"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/utils"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
+// ErrInterrupted is returned by Run if the context is canceled, the partial results are saved.
+var ErrInterrupted = errors.New("interrupted, partial results are saved")
+
 func Run(ctx context.Context, log *zap.Logger, opt Options) error {
 	if err := opt.SetFromEnv(); err != nil {
 		return fmt.Errorf("failed to set options from environment variables: %w", err)
@@ -29,9 +40,13 @@ func Run(ctx context.Context, log *zap.Logger, opt Options) error {
 }
 
 type runner struct {
-	log    *zap.Logger
-	reader reader.VotesReader
-	writer writer.VotesWriter
+	log             *zap.Logger
+	reader          reader.VotesReader
+	resolver        resolver.VotesResolver
+	writer          writer.VotesWriter
+	unmatchedWriter writer.UnmatchedVotesWriter
+	reviewWriter    writer.ReviewVotesWriter
+	auditWriter     writer.AuditWriter
 }
 
 func (r *runner) Run(ctx context.Context, opt Options) error {
@@ -39,11 +54,55 @@ func (r *runner) Run(ctx context.Context, opt Options) error {
 
 	log.Info("Reading votes")
 
-	votes, err := r.reader.ReadVotes(ctx, opt.UserID)
+	votes, unmatched, err := r.readAndResolve(ctx, opt)
+
+	if err != nil && ctx.Err() != nil {
+		log.Warn("Interrupted, writing the votes read so far")
+
+		if err := r.writePartial(ctx, log, opt, votes, unmatched); err != nil {
+			return err
+		}
+
+		return ErrInterrupted
+	}
+
+	if blockpage.IsBlocked(err) {
+		log.Error("Kinopoisk blocked the votes reading, writing the votes read so far: " + err.Error())
+
+		if err := r.writePartial(ctx, log, opt, votes, unmatched); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("votes export is incomplete, try again later, with a lower --rate or with the --cookies: %w", err)
+	}
+
 	if err != nil {
-		return fmt.Errorf("failed to read votes: %w", err)
+		return err
 	}
 
+	return r.write(ctx, log, opt, votes, unmatched)
+}
+
+// writePartial writes the votes read before the failure to the target file with the ".partial" suffix.
+func (r *runner) writePartial(
+	ctx context.Context,
+	log *zap.Logger,
+	opt Options,
+	votes kinopoisk.Votes,
+	unmatched kinopoisk.UnmatchedVotes,
+) error {
+	opt.TargetFile = utils.WithSuffix(opt.TargetFile, ".partial")
+
+	return r.write(context.WithoutCancel(ctx), log, opt, votes, unmatched)
+}
+
+func (r *runner) write(
+	ctx context.Context,
+	log *zap.Logger,
+	opt Options,
+	votes kinopoisk.Votes,
+	unmatched kinopoisk.UnmatchedVotes,
+) error {
 	log.Info("Writing votes")
 
 	if err := r.writer.WriteToFile(ctx, votes, opt.TargetFile, opt.TargetChunkSize); err != nil {
@@ -52,5 +111,101 @@ func (r *runner) Run(ctx context.Context, opt Options) error {
 
 	log.Info("Votes written to the " + opt.TargetFile)
 
+	unmatchedPath := utils.WithSuffix(opt.TargetFile, ".unmatched")
+
+	if len(unmatched) > 0 {
+		if err := r.unmatchedWriter.WriteToFile(ctx, unmatched, unmatchedPath); err != nil {
+			return fmt.Errorf("failed to write unmatched votes: %w", err)
+		}
+
+		log.Info("Unmatched votes written to the " + unmatchedPath)
+	} else if err := os.Remove(unmatchedPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// The unmatched votes of the previous run must not be taken for the current ones.
+		return fmt.Errorf("failed to remove stale unmatched votes file: %w", err)
+	}
+
+	if err := r.writeReview(ctx, log, opt, votes); err != nil {
+		return err
+	}
+
+	if opt.WriteAudit {
+		auditPath := utils.ReplaceExt(opt.TargetFile, ".audit.json")
+
+		if err := r.auditWriter.WriteToFile(ctx, votes, unmatched, auditPath); err != nil {
+			return fmt.Errorf("failed to write audit report: %w", err)
+		}
+
+		log.Info("Audit report written to the " + auditPath)
+	}
+
+	log.Info(fmt.Sprintf("Summary: %d vote(s) exported, %d vote(s) unmatched", len(votes), len(unmatched)))
+
+	return nil
+}
+
+// writeReview writes the votes matched with a known confidence below the review one to the CSV file
+// in the same format as the unmatched votes, so the wrong matches can be fixed by the fix command.
+func (r *runner) writeReview(ctx context.Context, log *zap.Logger, opt Options, votes kinopoisk.Votes) error {
+	review := make(kinopoisk.Votes, 0)
+
+	for _, vote := range votes {
+		if vote.ImdbMatch.IsLowConfidence(opt.IMDbReviewConfidence) {
+			review = append(review, vote)
+		}
+	}
+
+	reviewPath := utils.WithSuffix(opt.TargetFile, ".review")
+
+	if len(review) == 0 {
+		if err := os.Remove(reviewPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale review votes file: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := r.reviewWriter.WriteToFile(ctx, review, reviewPath); err != nil {
+		return fmt.Errorf("failed to write review votes: %w", err)
+	}
+
+	log.Info(fmt.Sprintf("%d vote(s) matched with a low confidence written to the %s", len(review), reviewPath))
+
 	return nil
 }
+
+func (r *runner) readAndResolve(
+	ctx context.Context,
+	opt Options,
+) (resolved kinopoisk.Votes, unmatched kinopoisk.UnmatchedVotes, err error) {
+	parsed := make(chan kinopoisk.Vote, opt.ResolveWorkers)
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		defer close(parsed)
+
+		if err := r.reader.ReadVotes(ctx, opt.UserID, parsed); err != nil {
+			return fmt.Errorf("failed to read votes: %w", err)
+		}
+
+		return nil
+	})
+
+	eg.Go(func() (err error) {
+		resolved, unmatched, err = r.resolver.Resolve(ctx, parsed)
+		if err != nil {
+			return fmt.Errorf("failed to resolve votes: %w", err)
+		}
+
+		return nil
+	})
+
+	err = eg.Wait()
+
+	// The votes read, but not received by the resolver before the failure are unmatched.
+	for vote := range parsed {
+		unmatched = append(unmatched, kinopoisk.UnmatchedVote{Vote: vote, Err: resolver.ErrNotResolved})
+	}
+
+	// The votes resolved before the failure are returned with the error.
+	return resolved, unmatched, err
+}
@@ -0,0 +1,93 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"go.uber.org/zap"
+)
+
+func NewJSONAuditWriter(log *zap.Logger) AuditWriter {
+	return &jsonAuditWriter{
+		log: log.With(zap.String("who", "jsonAuditWriter")),
+	}
+}
+
+// AuditWriter writes the report of how the IMDb IDs were resolved.
+type AuditWriter interface {
+	WriteToFile(
+		ctx context.Context,
+		votes kinopoisk.Votes,
+		unmatched kinopoisk.UnmatchedVotes,
+		targetPath string,
+	) error
+}
+
+type auditItem struct {
+	KinopoiskURL string       `json:"kinopoisk_url"`
+	Title        string       `json:"title"`
+	ImdbID       imdb.TitleID `json:"imdb_id,omitempty"`
+	Source       imdb.Source  `json:"source,omitempty"`
+	Confidence   float64      `json:"confidence"`
+	Candidates   int          `json:"candidates"`
+	Error        string       `json:"error,omitempty"`
+}
+
+type jsonAuditWriter struct {
+	log *zap.Logger
+}
+
+func (w *jsonAuditWriter) WriteToFile(
+	ctx context.Context,
+	votes kinopoisk.Votes,
+	unmatched kinopoisk.UnmatchedVotes,
+	targetPath string,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w.log.Info("Writing audit report", zap.String("target_path", targetPath))
+
+	items := make([]auditItem, 0, len(votes)+len(unmatched))
+
+	for _, vote := range votes {
+		items = append(items, newAuditItem(vote, nil))
+	}
+
+	for _, vote := range unmatched {
+		items = append(items, newAuditItem(vote.Vote, vote.Err))
+	}
+
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit report: %w", err)
+	}
+
+	if err := os.WriteFile(targetPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write audit report %s: %w", targetPath, err)
+	}
+
+	return nil
+}
+
+func newAuditItem(vote kinopoisk.Vote, err error) auditItem {
+	item := auditItem{
+		KinopoiskURL: vote.GetAbsoluteMovieURL(),
+		Title:        vote.GetOriginalTitle(),
+		ImdbID:       vote.ImdbID,
+		Source:       vote.ImdbMatch.Source,
+		Confidence:   vote.ImdbMatch.Confidence,
+		Candidates:   vote.ImdbMatch.Candidates,
+	}
+
+	if err != nil {
+		item.Error = err.Error()
+	}
+
+	return item
+}
@@ -0,0 +1,58 @@
+package writer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// writeCSVFile creates the CSV file with the header and the rows returned by the row func for each of n items.
+// The rows failed to be written are skipped with a warning, the file write failures are returned.
+func writeCSVFile(
+	log *zap.SugaredLogger,
+	targetPath string,
+	header []string,
+	n int,
+	row func(i int) []string,
+) (err error) {
+	log.Info("Creating file")
+
+	f, err := os.Create(targetPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", targetPath, err)
+	}
+
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close file %s: %w", targetPath, closeErr)
+		}
+	}()
+
+	writer := csv.NewWriter(f)
+
+	log.Info("Writing header")
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for i := 0; i < n; i++ {
+		log.Debugf("Writing row #%d", i)
+
+		if err := writer.Write(row(i)); err != nil {
+			log.Warnf("Failed to write row #%d: %s", i, err)
+
+			continue
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", targetPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"go.uber.org/zap"
+)
+
+func NewReviewCSVVotesWriter(log *zap.Logger) ReviewVotesWriter {
+	return &reviewCSVVotesWriter{
+		log: log.With(zap.String("who", "reviewCSVVotesWriter")),
+	}
+}
+
+// ReviewVotesWriter writes the votes matched with a low confidence to be checked by user.
+// The report has the same columns as the unmatched votes one, so it can be fixed the same way.
+type ReviewVotesWriter interface {
+	WriteToFile(ctx context.Context, votes kinopoisk.Votes, targetPath string) error
+}
+
+type reviewCSVVotesWriter struct {
+	log *zap.Logger
+}
+
+func (v *reviewCSVVotesWriter) WriteToFile(
+	ctx context.Context,
+	votes kinopoisk.Votes,
+	targetPath string,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	header := []string{
+		"IMDb ID",
+		"Kinopoisk URL",
+		"Title",
+		"Original Title",
+		"Year",
+		"Your Rating",
+		"Date Rated",
+		"Matched IMDb ID",
+		"Source",
+		"Confidence",
+	}
+
+	log := v.log.With(zap.String("target_path", targetPath)).Sugar()
+
+	return writeCSVFile(log, targetPath, header, len(votes), func(i int) []string {
+		vote := votes[i]
+
+		// The IMDb ID is left empty to be filled by user for the wrong matches only.
+		return []string{
+			"",
+			vote.GetAbsoluteMovieURL(),
+			vote.MovieNameRu,
+			vote.MovieNameOriginal,
+			vote.MovieYear,
+			fmt.Sprintf("%d", vote.Rate),
+			vote.Timestamp.Format("2006-01-02"),
+			vote.ImdbID.String(),
+			string(vote.ImdbMatch.Source),
+			strconv.FormatFloat(vote.ImdbMatch.Confidence, 'f', 2, 64),
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
+	"go.uber.org/zap"
+)
+
+func NewUnmatchedCSVVotesWriter(log *zap.Logger) UnmatchedVotesWriter {
+	return &unmatchedCSVVotesWriter{
+		log: log.With(zap.String("who", "unmatchedCSVVotesWriter")),
+	}
+}
+
+// UnmatchedVotesWriter writes the votes the IMDb ID was not found for.
+type UnmatchedVotesWriter interface {
+	WriteToFile(ctx context.Context, votes kinopoisk.UnmatchedVotes, targetPath string) error
+}
+
+type unmatchedCSVVotesWriter struct {
+	log *zap.Logger
+}
+
+func (v *unmatchedCSVVotesWriter) WriteToFile(
+	ctx context.Context,
+	votes kinopoisk.UnmatchedVotes,
+	targetPath string,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	header := []string{
+		"IMDb ID",
+		"Kinopoisk URL",
+		"Title",
+		"Original Title",
+		"Year",
+		"Your Rating",
+		"Date Rated",
+		"Error",
+	}
+
+	log := v.log.With(zap.String("target_path", targetPath)).Sugar()
+
+	return writeCSVFile(log, targetPath, header, len(votes), func(i int) []string {
+		vote := votes[i]
+
+		errText := ""
+		if vote.Err != nil {
+			errText = vote.Err.Error()
+		}
+
+		return []string{
+			"",
+			vote.GetAbsoluteMovieURL(),
+			vote.MovieNameRu,
+			vote.MovieNameOriginal,
+			vote.MovieYear,
+			fmt.Sprintf("%d", vote.Rate),
+			vote.Timestamp.Format("2006-01-02"),
+			errText,
+		}
+	})
+}
@@ -2,11 +2,7 @@ package writer
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/utils"
@@ -40,17 +36,8 @@ func (v *votesIMDbCSVVotesWriter) WriteToFile(
 
 	chunks := utils.Chunk(votes, chunkSize)
 
-	targetPath = utils.FixSeparators(targetPath)
-	dir := filepath.Dir(targetPath)
-	filename := filepath.Base(targetPath)
-	ext := filepath.Ext(filename)
-
-	if ext != "" {
-		filename, _ = strings.CutSuffix(filename, ext)
-	}
-
 	getTargetPath := func(chunkN int) string {
-		return filepath.Join(dir, filename+"."+fmt.Sprintf("%d", chunkN)+ext)
+		return utils.WithSuffix(targetPath, "."+fmt.Sprintf("%d", chunkN))
 	}
 
 	eg, ctx := errgroup.WithContext(ctx)
@@ -76,19 +63,6 @@ func (v *votesIMDbCSVVotesWriter) writeToFile(
 		return err
 	}
 
-	log := v.log.With(zap.String("target_path", targetPath)).Sugar()
-
-	log.Info("Creating file")
-
-	f, err := os.Create(targetPath)
-	if err != nil {
-		return fmt.Errorf("failed to create target file %s: %w", targetPath, err)
-	}
-
-	defer func() {
-		_ = f.Close()
-	}()
-
 	header := []string{
 		"Const",
 		"Your Rating",
@@ -99,19 +73,12 @@ func (v *votesIMDbCSVVotesWriter) writeToFile(
 		"Year", "Genres", "Num Votes", "Release Date", "Directors",
 	}
 
-	writer := csv.NewWriter(f)
-	defer writer.Flush()
-
-	log.Info("Writing header")
-
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
-	}
+	log := v.log.With(zap.String("target_path", targetPath)).Sugar()
 
-	for i, vote := range votes {
-		log.Debugf("Writing row #%d", i)
+	return writeCSVFile(log, targetPath, header, len(votes), func(i int) []string {
+		vote := votes[i]
 
-		row := []string{
+		return []string{
 			vote.ImdbID.String(),
 			fmt.Sprintf("%d", vote.Rate),
 			vote.Timestamp.Format("2006-01-02"),
@@ -120,13 +87,5 @@ func (v *votesIMDbCSVVotesWriter) writeToFile(
 			"", "", "", "",
 			"", "", "", "", "",
 		}
-
-		if err := writer.Write(row); err != nil {
-			log.Warnf("Failed to write row #%d: %s", i, err)
-
-			continue
-		}
-	}
-
-	return nil
+	})
 }
@@ -2,12 +2,14 @@ package writer_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
 
-	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/domain"
 	"github.com/kukymbr/kinopoiskexport/internal/app/kpvotes/writer"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk"
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,14 +27,14 @@ func TestVotesIMDbCSVVotesWriter_WriteToFile(t *testing.T) {
 	nowFmt := now.Format("2006-01-02")
 
 	wr := writer.NewIMDbCSVVotesWriter(logger.NewDefaultConsoleLogger(true))
-	votes := domain.Votes{
-		domain.Vote{
+	votes := kinopoisk.Votes{
+		kinopoisk.Vote{
 			MovieNameOriginal: "Test Movie 1",
 			Rate:              4,
 			Timestamp:         now,
 			MovieYear:         "2020",
 		},
-		domain.Vote{
+		kinopoisk.Vote{
 			MovieNameRu: "Тест Фильм 2",
 			Rate:        5,
 			Timestamp:   now,
@@ -40,7 +42,7 @@ func TestVotesIMDbCSVVotesWriter_WriteToFile(t *testing.T) {
 		},
 	}
 
-	err := wr.WriteToFile(context.Background(), votes, targetPath)
+	err := wr.WriteToFile(context.Background(), votes, targetPath, 0)
 
 	assert.NoError(t, err)
 	assert.FileExists(t, targetPath)
@@ -51,3 +53,117 @@ func TestVotesIMDbCSVVotesWriter_WriteToFile(t *testing.T) {
 	assert.Contains(t, string(content), ",4,"+nowFmt+",Test Movie 1 (2020),")
 	assert.Contains(t, string(content), ",5,"+nowFmt+",Тест Фильм 2 (2021),")
 }
+
+func TestUnmatchedCSVVotesWriter_WriteToFile(t *testing.T) {
+	targetPath := "./testdata/target/test_unmatched_csv_votes_writer.csv"
+
+	_ = os.Remove(targetPath)
+	t.Cleanup(func() {
+		_ = os.Remove(targetPath)
+	})
+
+	now := time.Now()
+	nowFmt := now.Format("2006-01-02")
+
+	wr := writer.NewUnmatchedCSVVotesWriter(logger.NewDefaultConsoleLogger(true))
+	votes := kinopoisk.UnmatchedVotes{
+		kinopoisk.UnmatchedVote{
+			Vote: kinopoisk.Vote{
+				MovieURL:          "/film/1/",
+				MovieNameRu:       "Тест Фильм 1",
+				MovieNameOriginal: "Test Movie 1",
+				Rate:              4,
+				Timestamp:         now,
+				MovieYear:         "2020",
+			},
+			Err: errors.New("no item"),
+		},
+	}
+
+	err := wr.WriteToFile(context.Background(), votes, targetPath)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, targetPath)
+
+	content, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(content), ",https://www.kinopoisk.ru/film/1/,Тест Фильм 1,Test Movie 1,2020,4,"+nowFmt+",no item")
+}
+
+func TestReviewCSVVotesWriter_WriteToFile(t *testing.T) {
+	targetPath := "./testdata/target/test_review_csv_votes_writer.csv"
+
+	_ = os.Remove(targetPath)
+	t.Cleanup(func() {
+		_ = os.Remove(targetPath)
+	})
+
+	now := time.Now()
+	nowFmt := now.Format("2006-01-02")
+
+	wr := writer.NewReviewCSVVotesWriter(logger.NewDefaultConsoleLogger(true))
+	votes := kinopoisk.Votes{
+		kinopoisk.Vote{
+			MovieURL:          "/film/1/",
+			MovieNameRu:       "Тест Фильм 1",
+			MovieNameOriginal: "Test Movie 1",
+			Rate:              4,
+			Timestamp:         now,
+			MovieYear:         "2020",
+			ImdbID:            "tt0000001",
+			ImdbMatch:         imdb.Match{Source: imdb.SourceFind, Confidence: 0.6},
+		},
+	}
+
+	err := wr.WriteToFile(context.Background(), votes, targetPath)
+
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(content), "IMDb ID,Kinopoisk URL,Title,Original Title,Year,")
+	assert.Contains(
+		t,
+		string(content),
+		",https://www.kinopoisk.ru/film/1/,Тест Фильм 1,Test Movie 1,2020,4,"+nowFmt+",tt0000001,find,0.60",
+	)
+}
+
+func TestJSONAuditWriter_WriteToFile(t *testing.T) {
+	targetPath := "./testdata/target/test_json_audit_writer.json"
+
+	_ = os.Remove(targetPath)
+	t.Cleanup(func() {
+		_ = os.Remove(targetPath)
+	})
+
+	wr := writer.NewJSONAuditWriter(logger.NewDefaultConsoleLogger(true))
+	votes := kinopoisk.Votes{
+		kinopoisk.Vote{
+			MovieURL:          "/film/1/",
+			MovieNameOriginal: "Test Movie 1",
+			MovieYear:         "2020",
+			ImdbID:            "tt0000001",
+			ImdbMatch:         imdb.Match{Source: imdb.SourceFind, Confidence: 0.75, Candidates: 3},
+		},
+	}
+	unmatched := kinopoisk.UnmatchedVotes{
+		kinopoisk.UnmatchedVote{
+			Vote: kinopoisk.Vote{MovieURL: "/film/2/", MovieNameRu: "Тест Фильм 2"},
+			Err:  errors.New("no item"),
+		},
+	}
+
+	err := wr.WriteToFile(context.Background(), votes, unmatched, targetPath)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(content), `"imdb_id": "tt0000001"`)
+	assert.Contains(t, string(content), `"source": "find"`)
+	assert.Contains(t, string(content), `"confidence": 0.75`)
+	assert.Contains(t, string(content), `"error": "no item"`)
+}
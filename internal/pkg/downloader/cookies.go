@@ -0,0 +1,203 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// netscapeHTTPOnlyPrefix marks the HttpOnly cookie lines in the Netscape cookies file.
+const netscapeHTTPOnlyPrefix = "#HttpOnly_"
+
+// LoadCookies reads the cookies from the Netscape cookies.txt or the JSON file exported by the browser.
+// As in the Netscape format, the domain of the host-only cookies has no leading dot.
+func LoadCookies(path string) ([]*http.Cookie, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies file %s: %w", path, err)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		cookies, err := parseJSONCookies(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON cookies file %s: %w", path, err)
+		}
+
+		return cookies, nil
+	}
+
+	cookies, err := parseNetscapeCookies(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cookies file %s: %w", path, err)
+	}
+
+	return cookies, nil
+}
+
+// NewCookieJar creates the http.CookieJar with the cookies of the domain and its subdomains,
+// the other cookies are dropped.
+func NewCookieJar(cookies []*http.Cookie, domain string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cookie := range cookies {
+		host := strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+
+		path := cookie.Path
+		if path == "" {
+			path = "/"
+		}
+
+		jarCookie := *cookie
+		if !strings.HasPrefix(cookie.Domain, ".") {
+			jarCookie.Domain = ""
+		}
+
+		jar.SetCookies(&url.URL{Scheme: "https", Host: host, Path: path}, []*http.Cookie{&jarCookie})
+	}
+
+	return jar, nil
+}
+
+// CookieValues returns the values of the cookies, e.g. to redact them from the logs.
+func CookieValues(cookies []*http.Cookie) []string {
+	values := make([]string, 0, len(cookies))
+
+	for _, cookie := range cookies {
+		values = append(values, cookie.Value)
+	}
+
+	return values
+}
+
+// parseNetscapeCookies parses the tab-separated lines:
+// domain, include subdomains, path, secure, expiration unix time, name, value.
+func parseNetscapeCookies(content []byte) ([]*http.Cookie, error) {
+	cookies := make([]*http.Cookie, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineN := 0
+
+	for scanner.Scan() {
+		lineN++
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, netscapeHTTPOnlyPrefix)
+
+		if httpOnly {
+			line = strings.TrimPrefix(line, netscapeHTTPOnlyPrefix)
+		}
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", lineN, len(fields))
+		}
+
+		cookie := &http.Cookie{
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			Name:     fields[5],
+			Value:    fields[6],
+		}
+
+		cookie.Domain = cookieDomain(fields[0], !strings.EqualFold(fields[1], "TRUE"))
+
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		cookies = append(cookies, cookie)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cookies, nil
+}
+
+// ioJSONCookie is a cookie in the format of the browser extensions' export.
+type ioJSONCookie struct {
+	Domain         string  `json:"domain"`
+	Path           string  `json:"path"`
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+	Secure         bool    `json:"secure"`
+	HTTPOnly       bool    `json:"httpOnly"`
+	HostOnly       bool    `json:"hostOnly"`
+	ExpirationDate float64 `json:"expirationDate"`
+}
+
+func parseJSONCookies(content []byte) ([]*http.Cookie, error) {
+	items := make([]ioJSONCookie, 0)
+
+	if content[0] == '{' {
+		var wrapper struct {
+			Cookies []ioJSONCookie `json:"cookies"`
+		}
+
+		if err := jsoniter.Unmarshal(content, &wrapper); err != nil {
+			return nil, err
+		}
+
+		items = wrapper.Cookies
+	} else if err := jsoniter.Unmarshal(content, &items); err != nil {
+		return nil, err
+	}
+
+	cookies := make([]*http.Cookie, 0, len(items))
+
+	for _, item := range items {
+		if item.Name == "" {
+			continue
+		}
+
+		cookie := &http.Cookie{
+			Domain:   cookieDomain(item.Domain, item.HostOnly),
+			Path:     item.Path,
+			Name:     item.Name,
+			Value:    item.Value,
+			Secure:   item.Secure,
+			HttpOnly: item.HTTPOnly,
+		}
+
+		if item.ExpirationDate > 0 {
+			cookie.Expires = time.Unix(int64(item.ExpirationDate), 0)
+		}
+
+		cookies = append(cookies, cookie)
+	}
+
+	return cookies, nil
+}
+
+// cookieDomain returns the domain with the leading dot, or without it for the host-only cookie.
+func cookieDomain(domain string, hostOnly bool) string {
+	domain = strings.TrimPrefix(domain, ".")
+
+	if hostOnly {
+		return domain
+	}
+
+	return "." + domain
+}
@@ -0,0 +1,103 @@
+package downloader_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCookies(t *testing.T) {
+	for _, path := range []string{"./testdata/cookies.txt", "./testdata/cookies.json"} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			cookies, err := downloader.LoadCookies(path)
+			require.NoError(t, err)
+
+			values := make(map[string]string)
+			for _, cookie := range cookies {
+				values[cookie.Domain+" "+cookie.Name] = cookie.Value
+			}
+
+			assert.Equal(t, "kp-session-secret", values[".kinopoisk.ru Session_id"])
+			assert.Equal(t, "ru-RU", values["www.kinopoisk.ru locale"])
+			assert.Equal(t, "imdb-session-secret", values[".imdb.com session-id"])
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	require.NoError(t, os.WriteFile(path, []byte(".kinopoisk.ru\tTRUE\t/\n"), 0644))
+
+	_, err := downloader.LoadCookies(path)
+	assert.Error(t, err)
+}
+
+func TestNewCookieJar(t *testing.T) {
+	cookies, err := downloader.LoadCookies("./testdata/cookies.txt")
+	require.NoError(t, err)
+
+	jar, err := downloader.NewCookieJar(cookies, "kinopoisk.ru")
+	require.NoError(t, err)
+
+	names := func(rawURL string) []string {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+
+		result := make([]string, 0)
+		for _, cookie := range jar.Cookies(u) {
+			result = append(result, cookie.Name)
+		}
+
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{"Session_id", "yandexuid", "locale"}, names("https://www.kinopoisk.ru/user/1/"))
+
+	// The host-only cookie is not sent to the other subdomains.
+	assert.ElementsMatch(t, []string{"Session_id", "yandexuid"}, names("https://hd.kinopoisk.ru/"))
+
+	// The other domains' cookies are dropped.
+	assert.Empty(t, names("https://www.imdb.com/"))
+}
+
+func TestStdDownloader_Download_Cookies(t *testing.T) {
+	received := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("Session_id")
+		if err == nil {
+			received <- cookie.Value
+		} else {
+			received <- ""
+		}
+	}))
+	defer server.Close()
+
+	cookies := []*http.Cookie{{Domain: "127.0.0.1", Name: "Session_id", Value: "secret"}}
+
+	jar, err := downloader.NewCookieJar(cookies, "127.0.0.1")
+	require.NoError(t, err)
+
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := downloader.NewStdDownloaderWithCookies(log, time.Minute, nil, jar)
+
+	body, err := dwn.Download(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Equal(t, "secret", <-received)
+
+	dwn = downloader.NewStdDownloader(log, time.Minute, nil)
+
+	body, err = dwn.Download(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Equal(t, "", <-received)
+}
@@ -7,15 +7,26 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// NewStdDownloader creates the Downloader with the timeout of each request including the body reading,
+// the request is canceled earlier if its context is done.
 func NewStdDownloader(log *zap.Logger, timeout time.Duration, proxyURL *url.URL) Downloader {
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	return NewStdDownloaderWithCookies(log, timeout, proxyURL, nil)
+}
+
+// NewStdDownloaderWithCookies creates the std Downloader sending the cookies of the jar.
+func NewStdDownloaderWithCookies(
+	log *zap.Logger,
+	timeout time.Duration,
+	proxyURL *url.URL,
+	jar http.CookieJar,
+) Downloader {
+	client := &http.Client{Jar: jar}
 
 	if proxyURL != nil {
 		client.Transport = &http.Transport{
@@ -24,10 +35,31 @@ func NewStdDownloader(log *zap.Logger, timeout time.Duration, proxyURL *url.URL)
 		}
 	}
 
-	return NewStdDownloaderWithClient(log, client)
+	dwn := newStdDownloader(log, client)
+	dwn.timeout = timeout
+
+	return dwn
+}
+
+// NewStdDownloaderWithUserAgent creates the std Downloader sending the userAgent instead of the Go's default one,
+// for the services requiring the client to introduce itself.
+func NewStdDownloaderWithUserAgent(
+	log *zap.Logger,
+	timeout time.Duration,
+	proxyURL *url.URL,
+	userAgent string,
+) Downloader {
+	dwn := NewStdDownloaderWithCookies(log, timeout, proxyURL, nil).(*stdDownloader)
+	dwn.userAgent = userAgent
+
+	return dwn
 }
 
 func NewStdDownloaderWithClient(log *zap.Logger, httpClient *http.Client) Downloader {
+	return newStdDownloader(log, httpClient)
+}
+
+func newStdDownloader(log *zap.Logger, httpClient *http.Client) *stdDownloader {
 	return &stdDownloader{
 		log:    log.With(zap.String("who", "stdDownloader")),
 		client: httpClient,
@@ -45,6 +77,10 @@ type Downloader interface {
 type stdDownloader struct {
 	log    *zap.Logger
 	client *http.Client
+	// timeout is a deadline of the request from its start to the body close, zero for none.
+	timeout time.Duration
+	// userAgent is a User-Agent header value, the Go's default one if empty.
+	userAgent string
 }
 
 func (d *stdDownloader) Download(ctx context.Context, pageURL string) (body io.ReadCloser, err error) {
@@ -60,28 +96,49 @@ func (d *stdDownloader) Download(ctx context.Context, pageURL string) (body io.R
 
 	log.Debug("Creating request")
 
-	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	cancel := context.CancelFunc(func() {})
+	if d.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
 	if err != nil {
+		cancel()
+
 		return nil, fmt.Errorf("create request to %s: %w", pageURL, err)
 	}
 
 	req.Header.Set("Accept", "text/html")
 	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9")
 
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+
 	log.Debug("Sending request")
 
 	resp, err := d.client.Do(req)
 	if err != nil {
+		cancel()
+
 		return nil, fmt.Errorf("request to '%s' failed: %w", pageURL, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("got non-OK response from '%s': %d", pageURL, resp.StatusCode)
+		_ = resp.Body.Close()
+		cancel()
+
+		return nil, &HTTPError{
+			URL:        pageURL,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
 	log.Debug("Downloaded")
 
-	return resp.Body, nil
+	// The body reading is aborted when the context is done, the timeout is released on close.
+	return &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}, nil
 }
 
 func (d *stdDownloader) Close() error {
@@ -91,3 +148,48 @@ func (d *stdDownloader) Close() error {
 
 	return nil
 }
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
+
+// HTTPError is returned by the Downloader if the response status is not OK.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is a delay requested by the server's Retry-After header, zero if not set.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("got non-OK response from '%s': %d", e.URL, e.StatusCode)
+}
+
+// parseRetryAfter parses the Retry-After header value in seconds or in the HTTP date format.
+func parseRetryAfter(val string, now time.Time) time.Duration {
+	if val == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(val); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(val); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,149 @@
+package downloader_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSlowServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "content")
+	})
+
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	mux.HandleFunc("/hung", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	mux.HandleFunc("/hung-body", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestStdDownloader_Download(t *testing.T) {
+	server := newSlowServer(t)
+	dwn := downloader.NewStdDownloader(logger.NewDefaultConsoleLogger(true), time.Minute, nil)
+
+	defer func() {
+		_ = dwn.Close()
+	}()
+
+	body, err := dwn.Download(context.Background(), server.URL+"/ok")
+	require.NoError(t, err)
+
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Equal(t, "content", string(content))
+
+	_, err = dwn.Download(context.Background(), server.URL+"/missing")
+	assert.Error(t, err)
+}
+
+func TestStdDownloader_Download_UserAgent(t *testing.T) {
+	userAgents := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		userAgents <- r.UserAgent()
+	}))
+	defer server.Close()
+
+	dwn := downloader.NewStdDownloaderWithUserAgent(
+		logger.NewDefaultConsoleLogger(true), time.Minute, nil, "kpvotes (https://example.com)",
+	)
+
+	body, err := dwn.Download(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	assert.Equal(t, "kpvotes (https://example.com)", <-userAgents)
+}
+
+func TestStdDownloader_Download_Canceled(t *testing.T) {
+	server := newSlowServer(t)
+	dwn := downloader.NewStdDownloader(logger.NewDefaultConsoleLogger(true), time.Minute, nil)
+
+	defer func() {
+		_ = dwn.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	_, err := dwn.Download(ctx, server.URL+"/hung")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestStdDownloader_Download_Timeout(t *testing.T) {
+	server := newSlowServer(t)
+	dwn := downloader.NewStdDownloader(logger.NewDefaultConsoleLogger(true), 50*time.Millisecond, nil)
+
+	defer func() {
+		_ = dwn.Close()
+	}()
+
+	_, err := dwn.Download(context.Background(), server.URL+"/hung")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The shorter context deadline wins over the timeout.
+	dwn = downloader.NewStdDownloader(logger.NewDefaultConsoleLogger(true), time.Minute, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = dwn.Download(ctx, server.URL+"/hung")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStdDownloader_Download_BodyCanceled(t *testing.T) {
+	server := newSlowServer(t)
+	dwn := downloader.NewStdDownloader(logger.NewDefaultConsoleLogger(true), time.Minute, nil)
+
+	defer func() {
+		_ = dwn.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	body, err := dwn.Download(ctx, server.URL+"/hung-body")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = io.ReadAll(body)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
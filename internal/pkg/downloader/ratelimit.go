@@ -0,0 +1,155 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// RateLimits are the max requests rates by the host.
+// The host limit applies to its subdomains too, the most specific host wins.
+type RateLimits map[string]rate.Limit
+
+// ParseRateLimits parses the comma-separated host=rate pairs,
+// the rate is a number of requests per period: "kinopoisk.ru=1/s,imdb.com=5/s,example.com=10/1m".
+func ParseRateLimits(val string) (RateLimits, error) {
+	limits := make(RateLimits)
+
+	for _, pair := range strings.Split(val, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		host, rateStr, ok := strings.Cut(pair, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid rate limit '%s', expected host=rate", pair)
+		}
+
+		limit, err := parseRate(strings.TrimSpace(rateStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit '%s': %w", pair, err)
+		}
+
+		limits[host] = limit
+	}
+
+	return limits, nil
+}
+
+// parseRate parses the "N/period" rate, the period is a duration, the "1" may be omitted: 5/s, 1/500ms, 30/1m.
+func parseRate(val string) (rate.Limit, error) {
+	countStr, periodStr, ok := strings.Cut(val, "/")
+	if !ok {
+		return 0, fmt.Errorf("expected count/period")
+	}
+
+	count, err := strconv.ParseFloat(countStr, 64)
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid requests count '%s'", countStr)
+	}
+
+	if periodStr != "" && (periodStr[0] < '0' || periodStr[0] > '9') {
+		periodStr = "1" + periodStr
+	}
+
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return 0, fmt.Errorf("invalid period '%s'", periodStr)
+	}
+
+	return rate.Limit(count / period.Seconds()), nil
+}
+
+// NewRateLimiter creates the RateLimiter with a token bucket for each host of the limits.
+// The RateLimiter is meant to be shared by all the Downloaders.
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	limiters := make(map[string]*rate.Limiter, len(limits))
+
+	for host, limit := range limits {
+		limiters[host] = rate.NewLimiter(limit, 1)
+	}
+
+	return &RateLimiter{limiters: limiters}
+}
+
+// RateLimiter limits the requests rates by the host.
+type RateLimiter struct {
+	limiters map[string]*rate.Limiter
+}
+
+// Wait blocks until the request to the URL is allowed or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context, pageURL string) error {
+	limiter := l.find(pageURL)
+	if limiter == nil {
+		return nil
+	}
+
+	return limiter.Wait(ctx)
+}
+
+// find returns the limiter of the URL's host or of its closest parent domain, nil if not limited.
+func (l *RateLimiter) find(pageURL string) *rate.Limiter {
+	if len(l.limiters) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	for host != "" {
+		if limiter, ok := l.limiters[host]; ok {
+			return limiter
+		}
+
+		_, host, _ = strings.Cut(host, ".")
+	}
+
+	return nil
+}
+
+// NewRateLimitDownloader creates the Downloader waiting for the limiter before each request.
+func NewRateLimitDownloader(log *zap.Logger, downloader Downloader, limiter *RateLimiter) Downloader {
+	return &rateLimitDownloader{
+		log:        log.With(zap.String("who", "rateLimitDownloader")),
+		downloader: downloader,
+		limiter:    limiter,
+	}
+}
+
+type rateLimitDownloader struct {
+	log        *zap.Logger
+	downloader Downloader
+	limiter    *RateLimiter
+}
+
+func (d *rateLimitDownloader) Download(ctx context.Context, pageURL string) (body io.ReadCloser, err error) {
+	start := time.Now()
+
+	if err := d.limiter.Wait(ctx, pageURL); err != nil {
+		return nil, fmt.Errorf("rate limit wait for %s: %w", pageURL, err)
+	}
+
+	if waited := time.Since(start); waited > 10*time.Millisecond {
+		d.log.Debug(fmt.Sprintf("Rate limited for %s", waited.Round(time.Millisecond)), zap.String("page_url", pageURL))
+	}
+
+	return d.downloader.Download(ctx, pageURL)
+}
+
+func (d *rateLimitDownloader) Close() error {
+	return d.downloader.Close()
+}
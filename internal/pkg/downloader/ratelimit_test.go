@@ -0,0 +1,91 @@
+package downloader_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := downloader.ParseRateLimits(" kinopoisk.ru=1/s, IMDb.com=5/s,example.com=30/1m,slow.com=1/500ms ")
+	require.NoError(t, err)
+
+	assert.Equal(t, downloader.RateLimits{
+		"kinopoisk.ru": 1,
+		"imdb.com":     5,
+		"example.com":  0.5,
+		"slow.com":     2,
+	}, limits)
+
+	limits, err = downloader.ParseRateLimits("")
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	for _, invalid := range []string{"kinopoisk.ru", "=1/s", "kinopoisk.ru=1", "kinopoisk.ru=0/s", "kinopoisk.ru=1/x"} {
+		_, err := downloader.ParseRateLimits(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := downloader.NewRateLimiter(downloader.RateLimits{"kinopoisk.ru": rate.Every(time.Minute)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, limiter.Wait(ctx, "https://www.kinopoisk.ru/film/1/"))
+
+	// The subdomains share the bucket of the parent host.
+	assert.Error(t, limiter.Wait(ctx, "https://kinopoisk.ru/film/2/"))
+
+	// The other hosts are not limited.
+	assert.NoError(t, limiter.Wait(ctx, "https://www.imdb.com/find/"))
+	assert.NoError(t, limiter.Wait(ctx, "https://notkinopoisk.ru/"))
+}
+
+func TestRateLimitDownloader_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "content")
+	}))
+	defer server.Close()
+
+	log := logger.NewDefaultConsoleLogger(true)
+	limiter := downloader.NewRateLimiter(downloader.RateLimits{"127.0.0.1": 20})
+
+	// The bucket is shared by the downloaders.
+	downloaders := []downloader.Downloader{
+		downloader.NewRateLimitDownloader(log, downloader.NewStdDownloader(log, time.Minute, nil), limiter),
+		downloader.NewRateLimitDownloader(log, downloader.NewStdDownloader(log, time.Minute, nil), limiter),
+	}
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+
+		go func(dwn downloader.Downloader) {
+			defer wg.Done()
+
+			body, err := dwn.Download(context.Background(), server.URL)
+			if assert.NoError(t, err) {
+				_ = body.Close()
+			}
+		}(downloaders[i%2])
+	}
+
+	wg.Wait()
+
+	// The first request is allowed immediately, the next five are 50ms apart.
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
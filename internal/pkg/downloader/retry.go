@@ -0,0 +1,165 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultRetryAttempts  = 4
+	DefaultRetryBaseDelay = time.Second
+	DefaultRetryMaxDelay  = time.Minute
+)
+
+// RetryOptions are the options of the retrying Downloader.
+type RetryOptions struct {
+	// Attempts is a maximum number of the download attempts, including the first one.
+	Attempts uint
+	// BaseDelay is a delay before the first retry, doubled for each next one.
+	BaseDelay time.Duration
+	// MaxDelay is a maximum delay between the attempts,
+	// the request is not retried if the server's Retry-After is longer.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryOptions returns the RetryOptions with the default values.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		Attempts:  DefaultRetryAttempts,
+		BaseDelay: DefaultRetryBaseDelay,
+		MaxDelay:  DefaultRetryMaxDelay,
+	}
+}
+
+// NewRetryDownloader creates the Downloader retrying the failed downloads
+// with the exponential backoff with jitter, honoring the server's Retry-After.
+// Only the retryable errors are retried, see IsRetryable.
+func NewRetryDownloader(log *zap.Logger, downloader Downloader, opt RetryOptions) Downloader {
+	if opt.Attempts == 0 {
+		opt.Attempts = 1
+	}
+
+	return &retryDownloader{
+		log:        log.With(zap.String("who", "retryDownloader")),
+		downloader: downloader,
+		opt:        opt,
+	}
+}
+
+type retryDownloader struct {
+	log        *zap.Logger
+	downloader Downloader
+	opt        RetryOptions
+}
+
+func (d *retryDownloader) Download(ctx context.Context, pageURL string) (body io.ReadCloser, err error) {
+	for attempt := uint(1); ; attempt++ {
+		body, err = d.downloader.Download(ctx, pageURL)
+		if err == nil || attempt >= d.opt.Attempts || ctx.Err() != nil || !IsRetryable(err) {
+			return body, err
+		}
+
+		delay, ok := d.delay(attempt, err)
+		if !ok {
+			return nil, err
+		}
+
+		d.log.Warn(
+			fmt.Sprintf("Download attempt %d of %d failed, retrying in %s", attempt, d.opt.Attempts, delay),
+			zap.String("page_url", pageURL),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the delay before the next attempt, false if the requested Retry-After is too long.
+func (d *retryDownloader) delay(attempt uint, err error) (time.Duration, bool) {
+	var httpErr *HTTPError
+
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		if d.opt.MaxDelay > 0 && httpErr.RetryAfter > d.opt.MaxDelay {
+			return 0, false
+		}
+
+		return httpErr.RetryAfter, true
+	}
+
+	delay := d.opt.BaseDelay << (attempt - 1)
+	if delay <= 0 || d.opt.MaxDelay > 0 && delay > d.opt.MaxDelay {
+		delay = d.opt.MaxDelay
+	}
+
+	// Equal jitter: half of the delay is fixed, the other half is random.
+	half := delay / 2
+	if half > 0 {
+		delay = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+
+	return delay, true
+}
+
+func (d *retryDownloader) Close() error {
+	return d.downloader.Close()
+}
+
+// IsRetryable returns true if the download error is transient:
+// the 408, 425, 429, 500, 502, 503, 504 response statuses,
+// the connection resets, refusals, unexpected EOFs and the network timeouts.
+// The context errors are not retried: the request that has spent its whole timeout
+// is not likely to succeed on the next attempt, and the retries would multiply the wait.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusRequestTimeout,
+			http.StatusTooEarly,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
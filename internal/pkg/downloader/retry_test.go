@@ -0,0 +1,150 @@
+package downloader_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlakyServer returns the server responding with the statuses in order, then with OK.
+func newFlakyServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	requests := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+
+			w.WriteHeader(statuses[n-1])
+
+			return
+		}
+
+		_, _ = io.WriteString(w, "content")
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func newTestRetryDownloader(attempts uint, maxDelay time.Duration) downloader.Downloader {
+	log := logger.NewDefaultConsoleLogger(true)
+
+	return downloader.NewRetryDownloader(
+		log,
+		downloader.NewStdDownloader(log, time.Minute, nil),
+		downloader.RetryOptions{Attempts: attempts, BaseDelay: time.Millisecond, MaxDelay: maxDelay},
+	)
+}
+
+func TestRetryDownloader_Download(t *testing.T) {
+	server, requests := newFlakyServer(t, "", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	dwn := newTestRetryDownloader(3, time.Second)
+
+	body, err := dwn.Download(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	assert.Equal(t, "content", string(content))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestRetryDownloader_Download_Exhausted(t *testing.T) {
+	server, requests := newFlakyServer(t, "", http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	dwn := newTestRetryDownloader(2, time.Second)
+
+	_, err := dwn.Download(context.Background(), server.URL)
+
+	var httpErr *downloader.HTTPError
+
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestRetryDownloader_Download_NotRetryable(t *testing.T) {
+	server, requests := newFlakyServer(t, "", http.StatusNotFound)
+	dwn := newTestRetryDownloader(3, time.Second)
+
+	_, err := dwn.Download(context.Background(), server.URL)
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryDownloader_Download_RetryAfter(t *testing.T) {
+	server, requests := newFlakyServer(t, "1", http.StatusTooManyRequests)
+	dwn := newTestRetryDownloader(2, 5*time.Second)
+
+	start := time.Now()
+
+	body, err := dwn.Download(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// The Retry-After longer than the max delay is not waited for.
+	server, requests = newFlakyServer(t, "120", http.StatusServiceUnavailable)
+
+	_, err = dwn.Download(context.Background(), server.URL)
+
+	var httpErr *downloader.HTTPError
+
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, 120*time.Second, httpErr.RetryAfter)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryDownloader_Download_Canceled(t *testing.T) {
+	server, requests := newFlakyServer(t, "1", http.StatusServiceUnavailable)
+	dwn := newTestRetryDownloader(3, 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := dwn.Download(ctx, server.URL)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		Err      error
+		Expected bool
+	}{
+		{Err: &downloader.HTTPError{StatusCode: http.StatusTooManyRequests}, Expected: true},
+		{Err: &downloader.HTTPError{StatusCode: http.StatusServiceUnavailable}, Expected: true},
+		{Err: &downloader.HTTPError{StatusCode: http.StatusNotFound}, Expected: false},
+		{Err: &downloader.HTTPError{StatusCode: http.StatusForbidden}, Expected: false},
+		{Err: fmt.Errorf("request failed: %w", syscall.ECONNRESET), Expected: true},
+		{Err: fmt.Errorf("request failed: %w", io.ErrUnexpectedEOF), Expected: true},
+		{Err: fmt.Errorf("request failed: %w", context.DeadlineExceeded), Expected: false},
+		{Err: fmt.Errorf("request failed: %w", context.Canceled), Expected: false},
+		{Err: fmt.Errorf("invalid URL"), Expected: false},
+		{Err: nil, Expected: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, downloader.IsRetryable(test.Err), fmt.Sprintf("%v", test.Err))
+	}
+}
@@ -0,0 +1,385 @@
+package imdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var boltCacheBucket = []byte("entries")
+
+// ErrCacheLocked is returned if the cache database is opened by another process.
+var ErrCacheLocked = errors.New("cache database is locked by another process")
+
+// ErrCacheNotDatabase is returned if the cache file is not a bbolt database, e.g. the JSON lines cache.
+var ErrCacheNotDatabase = errors.New("cache file is not a bolt database")
+
+// NewBoltCache opens the Cache persisted in the bbolt database file.
+// The entries are written through, so nothing is lost if the process crashes.
+// The cache must be closed after use.
+func NewBoltCache(log *zap.Logger, path string, missTTL time.Duration, policy ConflictPolicy) (*BoltCache, error) {
+	if isJSONLinesFile(path) {
+		return nil, fmt.Errorf(
+			"failed to open cache database %s, it is a JSON lines cache file, "+
+				"import it into a new database with the 'kpvotes cache import %s' command "+
+				"using another imdb_cache path and the bolt backend: %w",
+			path, path, ErrCacheNotDatabase,
+		)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf(
+			"failed to open cache database %s, wait for the other kpvotes run to finish or use another file: %w",
+			path, ErrCacheLocked,
+		)
+	}
+
+	if errors.Is(err, bolt.ErrInvalid) || errors.Is(err, bolt.ErrVersionMismatch) || errors.Is(err, bolt.ErrChecksum) {
+		return nil, fmt.Errorf("failed to open cache database %s: %w: %w", path, ErrCacheNotDatabase, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltCacheBucket)
+
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("failed to init cache database %s: %w", path, err)
+	}
+
+	return &BoltCache{
+		log:     log.With(zap.String("who", "imdb.BoltCache"), zap.String("path", path)),
+		missTTL: missTTL,
+		policy:  policy,
+		db:      db,
+	}, nil
+}
+
+// isJSONLinesFile returns true if the file starts with the JSON object, like the memory cache file does.
+func isJSONLinesFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return false
+	}
+
+	return buf[0] == '{'
+}
+
+// BoltCache is a Cache stored in the bbolt database, keyed by the CacheEntry.Key.
+type BoltCache struct {
+	log     *zap.Logger
+	missTTL time.Duration
+	policy  ConflictPolicy
+	db      *bolt.DB
+
+	mu      sync.Mutex
+	changed map[string]struct{}
+	removed map[string]struct{}
+}
+
+func (c *BoltCache) Store(ctx context.Context, entry CacheEntry) error {
+	if entry.IsMiss() && c.missTTL <= 0 {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		return putBoltCacheEntry(tx.Bucket(boltCacheBucket), entry)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.changed == nil {
+		c.changed = make(map[string]struct{})
+	}
+
+	c.changed[entry.Key()] = struct{}{}
+	delete(c.removed, entry.Key())
+
+	return nil
+}
+
+func (c *BoltCache) Get(ctx context.Context, query Query) (CacheEntry, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	var (
+		entry CacheEntry
+		ok    bool
+	)
+
+	err := c.db.View(func(tx *bolt.Tx) (err error) {
+		entry, ok, err = findBoltCacheEntry(tx.Bucket(boltCacheBucket), query)
+
+		return err
+	})
+	if err != nil {
+		return CacheEntry{}, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	if !ok || entry.IsExpiredMiss(c.missTTL) {
+		return CacheEntry{}, false, nil
+	}
+
+	return entry, true, nil
+}
+
+func (c *BoltCache) Invalidate(ctx context.Context, query Query) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var key string
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltCacheBucket)
+
+		entry, ok, err := findBoltCacheEntry(bucket, query)
+		if err != nil || !ok {
+			return err
+		}
+
+		key = entry.Key()
+
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil || key == "" {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.removed == nil {
+		c.removed = make(map[string]struct{})
+	}
+
+	c.removed[key] = struct{}{}
+
+	return nil
+}
+
+func (c *BoltCache) Entries(ctx context.Context) ([]CacheEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, 0)
+
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCacheBucket).ForEach(func(_, value []byte) error {
+			entry, err := decodeBoltCacheEntry(value)
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, entry)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ExportTitlesIDs writes the entries to the JSON lines file.
+// In the append mode only the entries stored since the previous export are appended,
+// otherwise the entries are merged with the ones stored to the file by the other processes.
+func (c *BoltCache) ExportTitlesIDs(ctx context.Context, targetPath string, isAppend bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]CacheEntry, 0)
+
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCacheBucket).ForEach(func(key, value []byte) error {
+			if isAppend {
+				if _, ok := c.changed[string(key)]; !ok {
+					return nil
+				}
+			}
+
+			entry, err := decodeBoltCacheEntry(value)
+			if err != nil {
+				return err
+			}
+
+			if c.missTTL > 0 && entry.IsExpiredMiss(c.missTTL) {
+				return nil
+			}
+
+			entries = append(entries, entry)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read cache entries: %w", err)
+	}
+
+	merge := cacheFileMerge{
+		policy: c.policy,
+		skip: func(entry CacheEntry) bool {
+			if _, ok := c.removed[entry.Key()]; ok {
+				return true
+			}
+
+			return c.missTTL > 0 && entry.IsExpiredMiss(c.missTTL)
+		},
+	}
+
+	if err := writeCacheFile(ctx, c.log, targetPath, entries, isAppend, merge); err != nil {
+		return err
+	}
+
+	c.changed = nil
+	c.removed = nil
+
+	return nil
+}
+
+// ImportTitlesIDs merges the entries from the JSON lines files in one transaction.
+func (c *BoltCache) ImportTitlesIDs(ctx context.Context, sourcePath string) error {
+	var conflicts []CacheConflict
+
+	err := c.db.Update(func(tx *bolt.Tx) (err error) {
+		bucket := tx.Bucket(boltCacheBucket)
+
+		conflicts, err = importCacheFiles(ctx, c.log, sourcePath, c.policy,
+			func(key string) (CacheEntry, bool, error) {
+				value := bucket.Get([]byte(key))
+				if value == nil {
+					return CacheEntry{}, false, nil
+				}
+
+				entry, err := decodeBoltCacheEntry(value)
+
+				return entry, err == nil, err
+			},
+			func(entry CacheEntry) error {
+				return putBoltCacheEntry(bucket, entry)
+			},
+		)
+
+		return err
+	})
+
+	logCacheConflicts(c.log, conflicts)
+
+	return err
+}
+
+func (c *BoltCache) Close() error {
+	return c.db.Close()
+}
+
+// putBoltCacheEntry puts the entry replacing the title-only one for the same title.
+func putBoltCacheEntry(bucket *bolt.Bucket, entry CacheEntry) error {
+	value, err := jsoniter.Marshal(newIOCacheItem(entry))
+	if err != nil {
+		return err
+	}
+
+	if entry.KinopoiskID != "" && entry.Title != "" {
+		if err := bucket.Delete([]byte(titleCacheKey(entry.Title))); err != nil {
+			return err
+		}
+	}
+
+	return bucket.Put([]byte(entry.Key()), value)
+}
+
+// findBoltCacheEntry looks up the entry by the kinopoisk film ID first, then by the title.
+// The title-only query also matches the entries stored by the kinopoisk film ID having the same title.
+func findBoltCacheEntry(bucket *bolt.Bucket, query Query) (CacheEntry, bool, error) {
+	keys := make([]string, 0, 2)
+
+	filmID := query.KinopoiskFilmID()
+	if filmID != "" {
+		keys = append(keys, kinopoiskCacheKey(filmID))
+	}
+
+	keys = append(keys, titleCacheKey(query.Title))
+
+	for _, key := range keys {
+		value := bucket.Get([]byte(key))
+		if value == nil {
+			continue
+		}
+
+		entry, err := decodeBoltCacheEntry(value)
+
+		return entry, err == nil, err
+	}
+
+	if filmID != "" || query.Title == "" {
+		return CacheEntry{}, false, nil
+	}
+
+	return scanBoltCacheTitle(bucket, query.Title)
+}
+
+// scanBoltCacheTitle returns the first entry by the key order having the title.
+func scanBoltCacheTitle(bucket *bolt.Bucket, title string) (CacheEntry, bool, error) {
+	cursor := bucket.Cursor()
+
+	for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+		entry, err := decodeBoltCacheEntry(value)
+		if err != nil {
+			return CacheEntry{}, false, err
+		}
+
+		if entry.Title == title {
+			return entry, true, nil
+		}
+	}
+
+	return CacheEntry{}, false, nil
+}
+
+func decodeBoltCacheEntry(value []byte) (CacheEntry, error) {
+	var item ioCacheItem
+
+	if err := jsoniter.Unmarshal(value, &item); err != nil {
+		return CacheEntry{}, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+
+	entry, _, err := item.toEntry()
+
+	return entry, err
+}
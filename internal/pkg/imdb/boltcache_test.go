@@ -0,0 +1,101 @@
+package imdb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltCache(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "cache.db")
+
+	found := imdb.Query{Title: "Found (2020)", KinopoiskURL: "/film/1/"}
+	missed := imdb.Query{Title: "Missed (2020)", KinopoiskURL: "/film/2/"}
+
+	cache, err := imdb.NewBoltCache(log, dbPath, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Store(ctx, imdb.NewCacheEntry(found, "tt0000001", imdb.Match{Source: imdb.SourceFind})))
+	require.NoError(t, cache.Store(ctx, imdb.NewCacheEntry(missed, "", imdb.Match{Source: imdb.SourceFind})))
+	require.NoError(t, cache.Close())
+
+	// Entries are written through and survive reopening without any export.
+	cache, err = imdb.NewBoltCache(log, dbPath, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, err)
+
+	defer func() {
+		_ = cache.Close()
+	}()
+
+	// The database is locked while it is open.
+	_, err = imdb.NewBoltCache(log, dbPath, time.Hour, imdb.ConflictPolicyNewest)
+	assert.ErrorIs(t, err, imdb.ErrCacheLocked)
+
+	entry, ok, err := cache.Get(ctx, found)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt0000001"), entry.ID)
+	assert.Equal(t, imdb.SourceFind, entry.Source)
+
+	entry, ok, err = cache.Get(ctx, missed)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, entry.IsMiss())
+
+	require.NoError(t, cache.Invalidate(ctx, missed))
+
+	_, ok, err = cache.Get(ctx, missed)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// JSON lines import and export.
+	legacyPath := filepath.Join(dir, "legacy.jsonl")
+	require.NoError(t, os.WriteFile(legacyPath, []byte(`{"title":"Legacy (2020)","id":"tt0000003"}`+"\n"), 0644))
+	require.NoError(t, cache.ImportTitlesIDs(ctx, legacyPath))
+
+	entry, ok, err = cache.Get(ctx, imdb.Query{Title: "Legacy (2020)", KinopoiskURL: "/film/3/"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt0000003"), entry.ID)
+
+	exportPath := filepath.Join(dir, "export.jsonl")
+	require.NoError(t, cache.ExportTitlesIDs(ctx, exportPath, false))
+
+	memory := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, memory.ImportTitlesIDs(ctx, exportPath))
+
+	entry, ok, err = memory.Get(ctx, found)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt0000001"), entry.ID)
+
+	assert.Equal(t, 3, countLines(t, exportPath), "header and two entries")
+}
+
+func TestBoltCache_WhenJSONLinesFile(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "cache.jsonl")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"title":"Legacy (2020)","id":"tt0000001"}`+"\n"), 0644))
+
+	_, err := imdb.NewBoltCache(log, jsonPath, time.Hour, imdb.ConflictPolicyNewest)
+	require.ErrorIs(t, err, imdb.ErrCacheNotDatabase)
+	assert.ErrorContains(t, err, "cache import")
+
+	otherPath := filepath.Join(dir, "other.db")
+	require.NoError(t, os.WriteFile(otherPath, make([]byte, 8192), 0644))
+
+	_, err = imdb.NewBoltCache(log, otherPath, time.Hour, imdb.ConflictPolicyNewest)
+	assert.ErrorIs(t, err, imdb.ErrCacheNotDatabase)
+}
@@ -1,221 +1,296 @@
 package imdb
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"os"
 	"sync"
+	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"go.uber.org/zap"
 )
 
-type ioTitleIDItem struct {
-	Title string `json:"title,omitempty"`
-	ID    string `json:"id,omitempty"`
-}
+// DefaultMissTTL is a default time the "not found" results are cached for.
+const DefaultMissTTL = 7 * 24 * time.Hour
+
+// ErrCachedMiss is returned by the cache DataLoader if the title is known to be not found.
+var ErrCachedMiss = fmt.Errorf("not found in previous run: %w", ErrNotFound)
 
-func NewMemoryCache(log *zap.Logger) Cache {
+// NewMemoryCache creates the in-memory Cache.
+// The "not found" results are cached for the missTTL, zero disables the misses caching.
+// The policy defines the entry kept if the imported one conflicts with the existing.
+func NewMemoryCache(log *zap.Logger, missTTL time.Duration, policy ConflictPolicy) Cache {
 	return &memoryCache{
-		log: log.With(zap.String("who", "imdb.memoryCache")),
+		log:     log.With(zap.String("who", "imdb.memoryCache")),
+		missTTL: missTTL,
+		policy:  policy,
 	}
 }
 
 type Cache interface {
-	StoreTitleID(ctx context.Context, title string, id TitleID) error
-	GetTitleID(ctx context.Context, title string) (TitleID, error)
-	InvalidateTitleID(ctx context.Context, title string) error
+	// Store stores the entry, the entry with an empty ID is a miss.
+	Store(ctx context.Context, entry CacheEntry) error
+	// Get returns the entry by the query's kinopoisk film ID or by the title.
+	// Expired misses are not returned.
+	Get(ctx context.Context, query Query) (entry CacheEntry, ok bool, err error)
+	// Invalidate removes the entry found by the query.
+	Invalidate(ctx context.Context, query Query) error
+	// Entries returns all the stored entries sorted by key, including the expired misses.
+	Entries(ctx context.Context) ([]CacheEntry, error)
 
 	ExportTitlesIDs(ctx context.Context, targetPath string, append bool) error
+	// ImportTitlesIDs merges the entries from the file, the directory files or the files matching the glob.
 	ImportTitlesIDs(ctx context.Context, sourcePath string) error
 }
 
-type memoryCache struct {
-	log       *zap.Logger
-	titlesIDs memoryTitleIDCache
+// NewCacheDataLoader creates the DataLoader reading the IDs from the Cache.
+// If retryMisses is false, the cached misses are returned as the ErrCachedMiss.
+func NewCacheDataLoader(cache Cache, retryMisses bool) DataLoader {
+	return &cacheDataLoader{cache: cache, retryMisses: retryMisses}
 }
 
-type memoryTitleIDCache struct {
-	sync.RWMutex
-	items map[string]TitleID
+// cacheDataLoader is a DataLoader reading the IDs from the Cache, used as a resolvers chain link.
+type cacheDataLoader struct {
+	cache       Cache
+	retryMisses bool
 }
 
-func (m *memoryCache) StoreTitleID(ctx context.Context, title string, id TitleID) error {
-	m.titlesIDs.Lock()
-	defer m.titlesIDs.Unlock()
+func (d *cacheDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	match := Match{Source: SourceCache}
 
-	if err := ctx.Err(); err != nil {
-		return err
+	entry, ok, err := d.cache.Get(ctx, query)
+	if err != nil {
+		return "", match, err
 	}
 
-	if m.titlesIDs.items == nil {
-		m.titlesIDs.items = make(map[string]TitleID)
+	if !ok {
+		return "", match, fmt.Errorf("not cached: %w", ErrNotFound)
 	}
 
-	m.titlesIDs.items[title] = id
+	if entry.IsMiss() {
+		if d.retryMisses {
+			return "", match, fmt.Errorf("cached miss is retried: %w", ErrNotFound)
+		}
 
-	return nil
-}
+		return "", match, ErrCachedMiss
+	}
 
-func (m *memoryCache) GetTitleID(ctx context.Context, title string) (TitleID, error) {
-	m.titlesIDs.RLock()
-	defer m.titlesIDs.RUnlock()
+	match.Confidence = entry.Confidence
 
-	if err := ctx.Err(); err != nil {
-		return "", err
-	}
+	return entry.ID, match, nil
+}
 
-	if m.titlesIDs.items == nil {
-		return "", nil
-	}
+type memoryCache struct {
+	log     *zap.Logger
+	missTTL time.Duration
+	policy  ConflictPolicy
 
-	if id, ok := m.titlesIDs.items[title]; ok {
-		return id, nil
-	}
+	mu      sync.RWMutex
+	entries map[string]CacheEntry
 
-	return "", nil
+	// changed are the keys of the entries stored since the import or the previous export.
+	changed map[string]struct{}
+	// removed are the keys of the entries invalidated since the previous export.
+	removed map[string]struct{}
 }
 
-func (m *memoryCache) InvalidateTitleID(ctx context.Context, title string) error {
-	m.titlesIDs.Lock()
-	defer m.titlesIDs.Unlock()
+func (m *memoryCache) Store(ctx context.Context, entry CacheEntry) error {
+	if entry.IsMiss() && m.missTTL <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	delete(m.titlesIDs.items, title)
+	m.store(entry)
 
-	return nil
-}
-
-func (m *memoryCache) ExportTitlesIDs(ctx context.Context, targetPath string, append bool) error {
-	if err := ctx.Err(); err != nil {
-		return err
+	if m.changed == nil {
+		m.changed = make(map[string]struct{})
 	}
 
-	log := m.log.Sugar().With("target", targetPath)
+	m.changed[entry.Key()] = struct{}{}
+	delete(m.removed, entry.Key())
 
-	log.Debug("Exporting cached")
+	return nil
+}
 
-	flags := os.O_RDWR | os.O_CREATE
-	if append {
-		flags |= os.O_APPEND
-	} else {
-		flags |= os.O_TRUNC
+// store adds the entry replacing the title-only one for the same title, the mu must be locked.
+func (m *memoryCache) store(entry CacheEntry) {
+	if m.entries == nil {
+		m.entries = make(map[string]CacheEntry)
 	}
 
-	f, err := os.OpenFile(targetPath, flags, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create target cache file %s: %w", targetPath, err)
+	if entry.KinopoiskID != "" && entry.Title != "" {
+		delete(m.entries, titleCacheKey(entry.Title))
 	}
 
-	defer func() {
-		_ = f.Close()
-	}()
+	m.entries[entry.Key()] = entry
+}
 
-	m.titlesIDs.RLock()
-	defer m.titlesIDs.RUnlock()
+func (m *memoryCache) Get(ctx context.Context, query Query) (CacheEntry, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	log.Debugf("Exporting %d item(s)", len(m.titlesIDs.items))
+	if err := ctx.Err(); err != nil {
+		return CacheEntry{}, false, err
+	}
 
-	if len(m.titlesIDs.items) == 0 {
-		return nil
+	entry, ok := m.find(query)
+	if !ok || m.isExpired(entry) {
+		return CacheEntry{}, false, nil
 	}
 
-	written := 0
+	return entry, true, nil
+}
 
-	for title, id := range m.titlesIDs.items {
-		if err := ctx.Err(); err != nil {
-			return err
-		}
+// find looks up the entry by the kinopoisk film ID first, then by the title, the mu must be locked.
+// The title-only query also matches the entries stored by the kinopoisk film ID having the same title.
+func (m *memoryCache) find(query Query) (CacheEntry, bool) {
+	filmID := query.KinopoiskFilmID()
 
-		item := ioTitleIDItem{
-			Title: title,
-			ID:    id.String(),
+	if filmID != "" {
+		if entry, ok := m.entries[kinopoiskCacheKey(filmID)]; ok {
+			return entry, true
 		}
+	}
 
-		row, err := jsoniter.MarshalToString(item)
-		if err != nil {
-			log.Warnf("Failed to marshal row (title=%s, id=%s): %s", title, id, err)
-
-			continue
-		}
+	if entry, ok := m.entries[titleCacheKey(query.Title)]; ok || filmID != "" || query.Title == "" {
+		return entry, ok
+	}
 
-		if _, err := f.WriteString(row + "\n"); err != nil {
-			log.Warnf("Failed to write row (title=%s, id=%s): %s", title, id, err)
+	var (
+		found CacheEntry
+		ok    bool
+	)
 
-			continue
+	// The smallest key is picked for the same titles, so the lookup doesn't depend on the map order.
+	for key, entry := range m.entries {
+		if entry.Title == query.Title && (!ok || key < found.Key()) {
+			found, ok = entry, true
 		}
-
-		written++
 	}
 
-	log.Debugf("Exported %d item(s)", written)
+	return found, ok
+}
 
-	return nil
+func (m *memoryCache) isExpired(entry CacheEntry) bool {
+	return entry.IsExpiredMiss(m.missTTL)
 }
 
-// TODO: import from directory
-func (m *memoryCache) ImportTitlesIDs(ctx context.Context, sourcePath string) error {
+func (m *memoryCache) Invalidate(ctx context.Context, query Query) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	log := m.log.Sugar().With("source", sourcePath)
+	if entry, ok := m.find(query); ok {
+		delete(m.entries, entry.Key())
 
-	log.Debug("Importing cached")
-
-	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-		log.Debug("No file exist")
+		if m.removed == nil {
+			m.removed = make(map[string]struct{})
+		}
 
-		return nil
+		m.removed[entry.Key()] = struct{}{}
 	}
 
-	f, err := os.Open(sourcePath)
-	if err != nil {
-		return fmt.Errorf("failed to open source cache file %s: %w", sourcePath, err)
-	}
+	return nil
+}
 
-	defer func() {
-		_ = f.Close()
-	}()
+func (m *memoryCache) Entries(ctx context.Context) ([]CacheEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	m.titlesIDs.Lock()
-	defer m.titlesIDs.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	if m.titlesIDs.items == nil {
-		m.titlesIDs.items = make(map[string]TitleID)
+	entries := make([]CacheEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
 	}
 
-	scanner := bufio.NewScanner(f)
+	sortCacheEntries(entries)
+
+	return entries, nil
+}
 
-	imported := 0
+// ExportTitlesIDs writes the entries to the file.
+// In the append (journal) mode only the entries stored since the import or the previous export
+// are appended, the invalidated ones are not removed from the file.
+// Otherwise, the file is atomically rewritten with all the entries
+// merged with the ones stored to the file by the other processes since the import.
+func (m *memoryCache) ExportTitlesIDs(ctx context.Context, targetPath string, isAppend bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	for scanner.Scan() {
-		var item ioTitleIDItem
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		if err := jsoniter.Unmarshal(scanner.Bytes(), &item); err != nil {
-			log.Warnf("Failed to unmarshal row: %s", err)
+	entries := make([]CacheEntry, 0, len(m.entries))
 
-			continue
+	for key, entry := range m.entries {
+		if isAppend {
+			if _, ok := m.changed[key]; !ok {
+				continue
+			}
 		}
 
-		if item.Title == "" || item.ID == "" {
-			log.Debugf("Item is skipped (title=%s, id=%s)", item.Title, item.ID)
+		// Without the TTL the misses are not used, but kept in the file.
+		if m.missTTL > 0 && entry.IsExpiredMiss(m.missTTL) {
+			continue
 		}
 
-		m.titlesIDs.items[item.Title] = TitleID(item.ID)
-
-		imported++
+		entries = append(entries, entry)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to scan %s: %w", sourcePath, err)
+	if err := writeCacheFile(ctx, m.log, targetPath, entries, isAppend, m.fileMerge()); err != nil {
+		return err
 	}
 
-	log.Debugf("Imported %d items", imported)
+	m.changed = nil
+	m.removed = nil
 
 	return nil
 }
+
+func (m *memoryCache) ImportTitlesIDs(ctx context.Context, sourcePath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conflicts, err := importCacheFiles(ctx, m.log, sourcePath, m.policy,
+		func(key string) (CacheEntry, bool, error) {
+			entry, ok := m.entries[key]
+
+			return entry, ok, nil
+		},
+		func(entry CacheEntry) error {
+			m.store(entry)
+
+			return nil
+		},
+	)
+
+	logCacheConflicts(m.log, conflicts)
+
+	return err
+}
+
+// fileMerge returns the merge of the cache file entries skipping the removed and expired ones, the mu must be locked.
+func (m *memoryCache) fileMerge() cacheFileMerge {
+	return cacheFileMerge{
+		policy: m.policy,
+		skip: func(entry CacheEntry) bool {
+			if _, ok := m.removed[entry.Key()]; ok {
+				return true
+			}
+
+			return m.missTTL > 0 && entry.IsExpiredMiss(m.missTTL)
+		},
+	}
+}
@@ -0,0 +1,361 @@
+package imdb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	found := imdb.Query{Title: "Found (2020)", KinopoiskURL: "/film/1/"}
+	missed := imdb.Query{Title: "Missed (2020)", KinopoiskURL: "/film/2/"}
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+
+	require.NoError(t, cache.Store(ctx, imdb.NewCacheEntry(
+		found, "tt0000001", imdb.Match{Source: imdb.SourceFind, Confidence: 0.75},
+	)))
+	require.NoError(t, cache.Store(ctx, imdb.NewCacheEntry(missed, "", imdb.Match{Source: imdb.SourceFind})))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, false))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Regexp(t, `^\{"version":2\}\n`, string(content))
+
+	imported := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, imported.ImportTitlesIDs(ctx, path))
+
+	entry, ok, err := imported.Get(ctx, found)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt0000001"), entry.ID)
+	assert.Equal(t, "1", entry.KinopoiskID)
+	assert.Equal(t, imdb.SourceFind, entry.Source)
+	assert.Equal(t, 0.75, entry.Confidence)
+	assert.WithinDuration(t, time.Now(), entry.ResolvedAt, time.Minute)
+
+	entry, ok, err = imported.Get(ctx, missed)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, entry.IsMiss())
+
+	// The entries are keyed by the kinopoisk ID, the same title of the other film is not matched.
+	_, ok, err = imported.Get(ctx, imdb.Query{Title: "Found (2020)", KinopoiskURL: "/film/3/"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemoryCache_ImportTitlesIDs_Legacy(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"title":"Legacy (2020)","id":"tt0000001"}`+"\n"), 0644))
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.ImportTitlesIDs(ctx, path))
+
+	entry, ok, err := cache.Get(ctx, imdb.Query{Title: "Legacy (2020)", KinopoiskURL: "/film/1/"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt0000001"), entry.ID)
+	assert.Empty(t, entry.KinopoiskID)
+
+	// The legacy title-only entry is replaced by the one keyed by the kinopoisk ID.
+	require.NoError(t, cache.Store(ctx, imdb.NewCacheEntry(
+		imdb.Query{Title: "Legacy (2020)", KinopoiskURL: "/film/1/"}, "tt0000002", imdb.Match{Source: imdb.SourceFind},
+	)))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, false))
+
+	exported, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(exported), "tt0000001")
+	assert.Contains(t, string(exported), `"kinopoisk_id":"1"`)
+}
+
+func TestMemoryCache_ImportTitlesIDs_MissTTL(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	resolvedAt := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+
+	content := `{"version":2}` + "\n" +
+		`{"kinopoisk_id":"1","title":"Old miss (2020)","resolved_at":"` + resolvedAt + `"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	query := imdb.Query{Title: "Old miss (2020)", KinopoiskURL: "/film/1/"}
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.ImportTitlesIDs(ctx, path))
+
+	_, ok, err := cache.Get(ctx, query)
+	require.NoError(t, err)
+	assert.False(t, ok, "expired miss")
+
+	longer := imdb.NewMemoryCache(log, 3*time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, longer.ImportTitlesIDs(ctx, path))
+
+	entry, ok, err := longer.Get(ctx, query)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, entry.IsMiss())
+}
+
+func TestCacheDataLoader_GetID_Legacy(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"title":"Legacy (2020)","id":"tt0000001"}`+"\n"), 0644))
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.ImportTitlesIDs(ctx, path))
+
+	id, match, err := imdb.NewCacheDataLoader(cache, false).GetID(ctx, imdb.Query{Title: "Legacy (2020)"})
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt0000001"), id)
+
+	// The legacy entry confidence is unknown, so the match is not written to the review report.
+	assert.Zero(t, match.Confidence)
+	assert.False(t, match.IsLowConfidence(0.75))
+	assert.True(t, imdb.Match{Source: imdb.SourceFind, Confidence: 0.5}.IsLowConfidence(0.75))
+}
+
+func TestMemoryCache_ImportTitlesIDs_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":100}`+"\n"), 0644))
+
+	cache := imdb.NewMemoryCache(logger.NewDefaultConsoleLogger(true), time.Hour, imdb.ConflictPolicyNewest)
+
+	assert.ErrorContains(t, cache.ImportTitlesIDs(context.Background(), path), "unsupported cache file version")
+}
+
+func TestMemoryCache_ExportTitlesIDs_Journal(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	first := imdb.Query{Title: "First (2020)", KinopoiskURL: "/film/1/"}
+	second := imdb.Query{Title: "Second (2020)", KinopoiskURL: "/film/2/"}
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.Store(ctx, imdb.NewCacheEntry(first, "tt0000001", imdb.Match{})))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, true))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, true))
+
+	next := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, next.ImportTitlesIDs(ctx, path))
+	require.NoError(t, next.Store(ctx, imdb.NewCacheEntry(second, "tt0000002", imdb.Match{})))
+	require.NoError(t, next.ExportTitlesIDs(ctx, path, true))
+
+	assert.Equal(t, 3, countLines(t, path), "header and two entries")
+
+	// The bloated file is rewritten without duplicates.
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(content, content...), 0644))
+
+	compacted := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, compacted.ImportTitlesIDs(ctx, path))
+	require.NoError(t, compacted.ExportTitlesIDs(ctx, path, false))
+
+	assert.Equal(t, 3, countLines(t, path))
+
+	entry, ok, err := compacted.Get(ctx, second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, imdb.TitleID("tt0000002"), entry.ID)
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return strings.Count(string(content), "\n")
+}
+
+func TestMemoryCache_ImportTitlesIDs_Directory(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"alice.jsonl": `{"version":2}
+{"kinopoisk_id":"1","title":"Film (2001)","id":"tt0000001","source":"override","resolved_at":"2024-01-01T00:00:00Z"}
+{"kinopoisk_id":"2","title":"Missed (2002)","resolved_at":"2024-01-01T00:00:00Z"}
+`,
+		"bob.jsonl": `{"version":2}
+{"kinopoisk_id":"1","title":"Film (2001)","id":"tt0000002","source":"find","resolved_at":"2024-02-01T00:00:00Z"}
+{"kinopoisk_id":"2","title":"Missed (2002)","id":"tt0000003","source":"find","resolved_at":"2023-01-01T00:00:00Z"}
+`,
+		".hidden.jsonl":     `{"kinopoisk_id":"1","title":"Film (2001)","id":"tt0000009"}`,
+		".alice.jsonl.lock": "",
+	}
+
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	tests := []struct {
+		Path     string
+		Policy   imdb.ConflictPolicy
+		Expected imdb.TitleID
+	}{
+		{Path: dir, Policy: imdb.ConflictPolicyNewest, Expected: "tt0000002"},
+		{Path: dir, Policy: imdb.ConflictPolicyPreferOverride, Expected: "tt0000001"},
+		{Path: filepath.Join(dir, "*.jsonl"), Policy: imdb.ConflictPolicyNewest, Expected: "tt0000002"},
+		{Path: filepath.Join(dir, "*"), Policy: imdb.ConflictPolicyNewest, Expected: "tt0000002"},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cache := imdb.NewMemoryCache(log, 0, test.Policy)
+			require.NoError(t, cache.ImportTitlesIDs(ctx, test.Path))
+
+			entry, ok, err := cache.Get(ctx, imdb.Query{KinopoiskURL: "https://www.kinopoisk.ru/film/1/"})
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, test.Expected, entry.ID)
+
+			// The found entry wins over the newer miss.
+			entry, ok, err = cache.Get(ctx, imdb.Query{KinopoiskURL: "https://www.kinopoisk.ru/film/2/"})
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, imdb.TitleID("tt0000003"), entry.ID)
+
+			// The lock files are neither read nor locked themselves.
+			paths, err := imdb.CacheSourcePaths(test.Path)
+			require.NoError(t, err)
+			assert.Equal(t, []string{filepath.Join(dir, "alice.jsonl"), filepath.Join(dir, "bob.jsonl")}, paths)
+			assert.NoFileExists(t, filepath.Join(dir, "..alice.jsonl.lock.lock"))
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	policy, err := imdb.ParseConflictPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, imdb.ConflictPolicyNewest, policy)
+
+	policy, err = imdb.ParseConflictPolicy("prefer-override")
+	require.NoError(t, err)
+	assert.Equal(t, imdb.ConflictPolicyPreferOverride, policy)
+
+	_, err = imdb.ParseConflictPolicy("oldest")
+	assert.Error(t, err)
+}
+
+func TestMemoryCache_ExportTitlesIDs_Shared(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	initial := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, initial.Store(ctx, imdb.CacheEntry{KinopoiskID: "1", Title: "One (2001)", ID: "tt0000001"}))
+	require.NoError(t, initial.Store(ctx, imdb.CacheEntry{KinopoiskID: "2", Title: "Two (2002)", ID: "tt0000002"}))
+	require.NoError(t, initial.ExportTitlesIDs(ctx, path, false))
+
+	runs := make([]imdb.Cache, 0, 5)
+
+	for i := 0; i < 5; i++ {
+		cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+		require.NoError(t, cache.ImportTitlesIDs(ctx, path))
+
+		id := strconv.Itoa(10 + i)
+		require.NoError(t, cache.Store(ctx, imdb.CacheEntry{
+			KinopoiskID: id, Title: "Film " + id, ID: imdb.TitleID("tt00000" + id), ResolvedAt: time.Now(),
+		}))
+
+		runs = append(runs, cache)
+	}
+
+	errs := make(chan error, len(runs))
+
+	for _, cache := range runs {
+		go func(cache imdb.Cache) {
+			errs <- cache.ExportTitlesIDs(ctx, path, false)
+		}(cache)
+	}
+
+	for range runs {
+		require.NoError(t, <-errs)
+	}
+
+	merged := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, merged.ImportTitlesIDs(ctx, path))
+
+	entries, err := merged.Entries(ctx)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID.String())
+	}
+
+	assert.Contains(t, ids, "tt0000001")
+	assert.Contains(t, ids, "tt0000002")
+
+	for i := 0; i < 5; i++ {
+		assert.Contains(t, ids, "tt00000"+strconv.Itoa(10+i))
+	}
+}
+
+func TestMemoryCache_ExportTitlesIDs_SharedRemoved(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.Store(ctx, imdb.CacheEntry{KinopoiskID: "1", Title: "One (2001)", ID: "tt0000001"}))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, false))
+
+	other := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, other.Store(ctx, imdb.CacheEntry{KinopoiskID: "2", Title: "Two (2002)", ID: "tt0000002"}))
+	require.NoError(t, other.ExportTitlesIDs(ctx, path, false))
+
+	require.NoError(t, cache.Invalidate(ctx, imdb.Query{KinopoiskURL: "1"}))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, false))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "tt0000001")
+	assert.Contains(t, string(content), "tt0000002")
+}
+
+func TestMemoryCache_ExportTitlesIDs_SharedSameTitle(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	cache := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, cache.Store(ctx, imdb.CacheEntry{KinopoiskID: "1", Title: "Hamlet (1990)", ID: "tt0099726"}))
+	require.NoError(t, cache.ExportTitlesIDs(ctx, path, false))
+
+	other := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, other.Store(ctx, imdb.CacheEntry{KinopoiskID: "2", Title: "Hamlet (1990)", ID: "tt0099727"}))
+	require.NoError(t, other.ExportTitlesIDs(ctx, path, false))
+
+	merged := imdb.NewMemoryCache(log, time.Hour, imdb.ConflictPolicyNewest)
+	require.NoError(t, merged.ImportTitlesIDs(ctx, path))
+
+	for filmID, expected := range map[string]imdb.TitleID{"1": "tt0099726", "2": "tt0099727"} {
+		entry, ok, err := merged.Get(ctx, imdb.Query{Title: "Hamlet (1990)", KinopoiskURL: filmID})
+		require.NoError(t, err)
+		require.True(t, ok, filmID)
+		assert.Equal(t, expected, entry.ID, filmID)
+	}
+}
@@ -0,0 +1,148 @@
+package imdb
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// cacheFormatVersion is a version of the cache file format written by the export.
+//
+// Version 2 starts with the {"version":2} header line
+// and has the resolving metadata in the entries.
+// Version 1 (legacy) entries are the bare {"title","id"} objects,
+// they are migrated on import.
+const cacheFormatVersion = 2
+
+// CacheEntry is a cached IMDb ID resolving result.
+type CacheEntry struct {
+	// KinopoiskID is a kinopoisk film ID, empty for the entries migrated from the legacy format.
+	KinopoiskID string
+	// Title is a title in the "Original title (year)" format.
+	Title string
+
+	// ID is an IMDb title ID, empty if the title is not found.
+	ID TitleID
+
+	// Source is a resolver the ID was found by, empty if unknown.
+	Source Source
+	// Confidence is a resolver's confidence, zero if unknown.
+	Confidence float64
+	// ResolvedAt is a time of the resolving, zero if unknown.
+	ResolvedAt time.Time
+}
+
+// NewCacheEntry creates the CacheEntry for the query resolving result, empty ID is a miss.
+func NewCacheEntry(query Query, id TitleID, match Match) CacheEntry {
+	return CacheEntry{
+		KinopoiskID: query.KinopoiskFilmID(),
+		Title:       query.Title,
+		ID:          id,
+		Source:      match.Source,
+		Confidence:  match.Confidence,
+		ResolvedAt:  time.Now(),
+	}
+}
+
+// IsMiss returns true if the entry is a "not found" result.
+func (e CacheEntry) IsMiss() bool {
+	return e.ID == ""
+}
+
+// IsExpiredMiss returns true if the entry is a miss older than the ttl; all misses are expired without the ttl.
+func (e CacheEntry) IsExpiredMiss(ttl time.Duration) bool {
+	if !e.IsMiss() {
+		return false
+	}
+
+	return ttl <= 0 || time.Since(e.ResolvedAt) > ttl
+}
+
+// Key returns the entry's key: the kinopoisk film ID if known, the title otherwise.
+func (e CacheEntry) Key() string {
+	if e.KinopoiskID != "" {
+		return kinopoiskCacheKey(e.KinopoiskID)
+	}
+
+	return titleCacheKey(e.Title)
+}
+
+func kinopoiskCacheKey(filmID string) string {
+	return "kp:" + filmID
+}
+
+func titleCacheKey(title string) string {
+	return "title:" + title
+}
+
+// ioCacheItem is a cache file line, either the header or the entry of any format version.
+type ioCacheItem struct {
+	Version int `json:"version,omitempty"`
+
+	KinopoiskID string  `json:"kinopoisk_id,omitempty"`
+	Title       string  `json:"title,omitempty"`
+	ID          string  `json:"id,omitempty"`
+	Source      string  `json:"source,omitempty"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	ResolvedAt  string  `json:"resolved_at,omitempty"`
+}
+
+func newIOCacheItem(entry CacheEntry) ioCacheItem {
+	item := ioCacheItem{
+		KinopoiskID: entry.KinopoiskID,
+		Title:       entry.Title,
+		ID:          entry.ID.String(),
+		Source:      string(entry.Source),
+		Confidence:  entry.Confidence,
+	}
+
+	if !entry.ResolvedAt.IsZero() {
+		item.ResolvedAt = entry.ResolvedAt.UTC().Format(time.RFC3339)
+	}
+
+	return item
+}
+
+// toEntry converts the item to the CacheEntry, isLegacy is true if the item is in the legacy format.
+func (i ioCacheItem) toEntry() (entry CacheEntry, isLegacy bool, err error) {
+	if i.Title == "" && i.KinopoiskID == "" {
+		return CacheEntry{}, false, errors.New("no title and kinopoisk ID")
+	}
+
+	entry = CacheEntry{
+		KinopoiskID: i.KinopoiskID,
+		Title:       i.Title,
+		ID:          TitleID(i.ID),
+		Source:      Source(i.Source),
+		Confidence:  i.Confidence,
+	}
+
+	if i.ResolvedAt != "" {
+		entry.ResolvedAt, err = time.Parse(time.RFC3339, i.ResolvedAt)
+		if err != nil {
+			return CacheEntry{}, false, err
+		}
+
+		return entry, false, nil
+	}
+
+	// The legacy format has no misses.
+	if entry.IsMiss() {
+		return CacheEntry{}, true, errors.New("no ID")
+	}
+
+	return entry, true, nil
+}
+
+func writeCacheHeader(w io.Writer) error {
+	row, err := jsoniter.MarshalToString(ioCacheItem{Version: cacheFormatVersion})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, row+"\n")
+
+	return err
+}
@@ -0,0 +1,344 @@
+package imdb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// importCacheFiles reads the cache files by the sourcePath merging their entries with the existing ones.
+// The get func returns the existing entry by key, the store func stores the merged one.
+func importCacheFiles(
+	ctx context.Context,
+	log *zap.Logger,
+	sourcePath string,
+	policy ConflictPolicy,
+	get func(key string) (CacheEntry, bool, error),
+	store func(entry CacheEntry) error,
+) ([]CacheConflict, error) {
+	paths, err := CacheSourcePaths(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := make([]CacheConflict, 0)
+
+	merge := func(entry CacheEntry) error {
+		existing, ok, err := get(entry.Key())
+		if err == nil && !ok && entry.KinopoiskID != "" {
+			existing, ok, err = get(titleCacheKey(entry.Title))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			var conflict *CacheConflict
+
+			entry, conflict = policy.merge(existing, entry)
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+			}
+		}
+
+		return store(entry)
+	}
+
+	for _, path := range paths {
+		if err := readCacheFile(ctx, log, path, merge); err != nil {
+			return conflicts, err
+		}
+	}
+
+	return conflicts, nil
+}
+
+// readCacheFile reads the JSON lines cache file of any format version passing the entries to the store func.
+// The file is read under the shared lock. The missing file is not an error.
+func readCacheFile(
+	ctx context.Context,
+	log *zap.Logger,
+	sourcePath string,
+	store func(entry CacheEntry) error,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	unlock, err := utils.LockFile(sourcePath, false)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = unlock()
+	}()
+
+	return scanCacheFile(log, sourcePath, store)
+}
+
+// scanCacheFile reads the cache file without locking.
+func scanCacheFile(log *zap.Logger, sourcePath string, store func(entry CacheEntry) error) error {
+	sugar := log.Sugar().With("source", sourcePath)
+
+	sugar.Debug("Importing cached")
+
+	f, err := os.Open(sourcePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		sugar.Debug("No file exist")
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open source cache file %s: %w", sourcePath, err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+
+	imported := 0
+	migrated := 0
+
+	for scanner.Scan() {
+		var item ioCacheItem
+
+		if err := jsoniter.Unmarshal(scanner.Bytes(), &item); err != nil {
+			sugar.Warnf("Failed to unmarshal row: %s", err)
+
+			continue
+		}
+
+		if item.Version > 0 {
+			if item.Version > cacheFormatVersion {
+				return fmt.Errorf("unsupported cache file version %d in %s", item.Version, sourcePath)
+			}
+
+			continue
+		}
+
+		entry, isLegacy, err := item.toEntry()
+		if err != nil {
+			sugar.Debugf("Item is skipped (title=%s, id=%s): %s", item.Title, item.ID, err)
+
+			continue
+		}
+
+		if isLegacy {
+			migrated++
+		}
+
+		if err := store(entry); err != nil {
+			return err
+		}
+
+		imported++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to scan %s: %w", sourcePath, err)
+	}
+
+	sugar.Debugf("Imported %d items, %d migrated from the legacy format", imported, migrated)
+
+	return nil
+}
+
+// cacheFileMerge defines how the entries written to the cache file by the other processes are merged.
+type cacheFileMerge struct {
+	policy ConflictPolicy
+	// skip returns true for the file entry that must not be kept: removed by this process or expired.
+	skip func(entry CacheEntry) bool
+}
+
+// writeCacheFile writes the entries sorted by key to the JSON lines cache file under the exclusive lock.
+// If isAppend is true, the entries are appended to the file.
+// Otherwise, the entries are merged with the ones currently in the file, and the file is atomically rewritten.
+func writeCacheFile(
+	ctx context.Context,
+	log *zap.Logger,
+	targetPath string,
+	entries []CacheEntry,
+	isAppend bool,
+	merge cacheFileMerge,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sugar := log.Sugar().With("target", targetPath, "append", isAppend)
+
+	unlock, err := utils.LockFile(targetPath, true)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = unlock()
+	}()
+
+	if !isAppend {
+		var conflicts []CacheConflict
+
+		entries, conflicts, err = mergeCacheFile(log, targetPath, entries, merge)
+		if err != nil {
+			return err
+		}
+
+		logCacheConflicts(log, conflicts)
+	}
+
+	sugar.Debugf("Exporting %d item(s)", len(entries))
+
+	sortCacheEntries(entries)
+
+	if isAppend {
+		err = appendCacheEntries(ctx, targetPath, entries)
+	} else {
+		err = utils.WriteFileAtomic(targetPath, 0644, func(w io.Writer) error {
+			return writeCacheEntries(ctx, w, entries, true)
+		})
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to export cache to %s: %w", targetPath, err)
+	}
+
+	sugar.Debugf("Exported %d item(s)", len(entries))
+
+	return nil
+}
+
+// mergeCacheFile adds the file entries written by the other processes to the entries, the file must be locked.
+// The entries of the same film are merged by the policy, the found entry always wins over the miss.
+// The entries of the same kinopoisk ID are the same film; the title-only legacy entry is the same film
+// as the entry of its title, but the entries of the different kinopoisk IDs are never merged by the title.
+func mergeCacheFile(
+	log *zap.Logger,
+	targetPath string,
+	entries []CacheEntry,
+	merge cacheFileMerge,
+) ([]CacheEntry, []CacheConflict, error) {
+	index := make(map[string]int, len(entries))
+	titles := make(map[string][]int, len(entries))
+
+	add := func(i int, entry CacheEntry) {
+		index[entry.Key()] = i
+
+		if entry.KinopoiskID != "" {
+			titles[entry.Title] = append(titles[entry.Title], i)
+		}
+	}
+
+	for i, entry := range entries {
+		add(i, entry)
+	}
+
+	conflicts := make([]CacheConflict, 0)
+
+	err := scanCacheFile(log, targetPath, func(stored CacheEntry) error {
+		if merge.skip != nil && merge.skip(stored) {
+			return nil
+		}
+
+		i, ok := index[stored.Key()]
+
+		switch {
+		case ok:
+		case stored.KinopoiskID != "":
+			// The title key is the key of the title-only entry only.
+			i, ok = index[titleCacheKey(stored.Title)]
+		case len(titles[stored.Title]) == 1:
+			i, ok = titles[stored.Title][0], true
+		}
+
+		if !ok {
+			add(len(entries), stored)
+			entries = append(entries, stored)
+
+			return nil
+		}
+
+		kept, conflict := merge.policy.merge(stored, entries[i])
+		if conflict != nil {
+			conflicts = append(conflicts, *conflict)
+		}
+
+		entries[i] = kept
+		add(i, kept)
+
+		return nil
+	})
+
+	return entries, conflicts, err
+}
+
+func appendCacheEntries(ctx context.Context, targetPath string, entries []CacheEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := writeCacheEntries(ctx, f, entries, stat.Size() == 0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+func writeCacheEntries(ctx context.Context, w io.Writer, entries []CacheEntry, withHeader bool) error {
+	if withHeader {
+		if err := writeCacheHeader(w); err != nil {
+			return fmt.Errorf("failed to write cache file header: %w", err)
+		}
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		row, err := jsoniter.MarshalToString(newIOCacheItem(entry))
+		if err != nil {
+			return fmt.Errorf("failed to marshal row (title=%s, id=%s): %w", entry.Title, entry.ID, err)
+		}
+
+		if _, err := io.WriteString(w, row+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortCacheEntries(entries []CacheEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key() < entries[j].Key()
+	})
+}
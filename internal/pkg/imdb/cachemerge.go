@@ -0,0 +1,158 @@
+package imdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ConflictPolicy defines which of the cached entries of the same film with different IDs is kept on import.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyNewest keeps the most recently resolved entry.
+	ConflictPolicyNewest ConflictPolicy = "newest"
+	// ConflictPolicyPreferOverride keeps the manually set entry, the most recently resolved one otherwise.
+	ConflictPolicyPreferOverride ConflictPolicy = "prefer-override"
+)
+
+// ParseConflictPolicy returns the policy by its name, the empty name is the ConflictPolicyNewest.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(name); policy {
+	case "":
+		return ConflictPolicyNewest, nil
+	case ConflictPolicyNewest, ConflictPolicyPreferOverride:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown cache conflict policy '%s'", name)
+	}
+}
+
+// merge returns the entry to keep of the existing and the imported ones.
+// The found entry always wins over the miss.
+// The conflict is not nil if both entries are found with the different IDs.
+func (p ConflictPolicy) merge(existing CacheEntry, imported CacheEntry) (CacheEntry, *CacheConflict) {
+	if existing.IsMiss() || imported.IsMiss() {
+		if existing.IsMiss() && imported.IsMiss() {
+			return p.newest(existing, imported), nil
+		}
+
+		if existing.IsMiss() {
+			return imported, nil
+		}
+
+		return existing, nil
+	}
+
+	keepExisting := existing.ResolvedAt.After(imported.ResolvedAt)
+
+	isExistingOverride := existing.Source == SourceOverride
+	isImportedOverride := imported.Source == SourceOverride
+
+	if p == ConflictPolicyPreferOverride && isExistingOverride != isImportedOverride {
+		keepExisting = isExistingOverride
+	}
+
+	kept, dropped := imported, existing
+	if keepExisting {
+		kept, dropped = existing, imported
+	}
+
+	if existing.ID == imported.ID {
+		return kept, nil
+	}
+
+	return kept, &CacheConflict{Kept: kept, Dropped: dropped}
+}
+
+// newest returns the latest resolved entry, the imported one if the times are equal.
+func (p ConflictPolicy) newest(existing CacheEntry, imported CacheEntry) CacheEntry {
+	if existing.ResolvedAt.After(imported.ResolvedAt) {
+		return existing
+	}
+
+	return imported
+}
+
+// CacheConflict is a pair of the cached entries of the same film with the different IDs.
+type CacheConflict struct {
+	Kept    CacheEntry
+	Dropped CacheEntry
+}
+
+func (c CacheConflict) String() string {
+	return fmt.Sprintf(
+		"'%s': %s (%s) is kept, %s (%s) is dropped",
+		c.Kept.Title, c.Kept.ID, c.Kept.Source, c.Dropped.ID, c.Dropped.Source,
+	)
+}
+
+func logCacheConflicts(log *zap.Logger, conflicts []CacheConflict) {
+	for _, conflict := range conflicts {
+		log.Warn("Conflicting cached IMDb IDs for " + conflict.String())
+	}
+
+	if len(conflicts) > 0 {
+		log.Warn(fmt.Sprintf("%d cache conflict(s) resolved", len(conflicts)))
+	}
+}
+
+// CacheSourcePaths returns the cache files by the path:
+// the regular files of the directory, the files matching the glob pattern or the path itself.
+func CacheSourcePaths(path string) ([]string, error) {
+	if IsCachePattern(path) {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache files pattern %s: %w", path, err)
+		}
+
+		return visibleFiles(matches), nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil || !stat.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory %s: %w", path, err)
+	}
+
+	paths := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		paths = append(paths, filepath.Join(path, entry.Name()))
+	}
+
+	return visibleFiles(paths), nil
+}
+
+// IsCachePattern returns true if the path is a glob pattern.
+func IsCachePattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// visibleFiles returns the regular files of the paths skipping the hidden ones,
+// like the lock files and the temp files of the atomic writing.
+func visibleFiles(paths []string) []string {
+	files := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			continue
+		}
+
+		if stat, err := os.Stat(path); err == nil && stat.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files
+}
@@ -0,0 +1,94 @@
+package imdb
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	candidateYearRx    = regexp.MustCompile(`^([0-9]{4})`)
+	candidateEpisodeRx = regexp.MustCompile(`^S[0-9]+\.E[0-9]+$`)
+)
+
+// Candidate is a title found by the query.
+type Candidate struct {
+	ID    TitleID
+	Title string
+	Year  string
+
+	// Type is a title type like "TV Series" or "Short", empty for the feature films.
+	Type string
+
+	// Position is a 1-based position of the candidate in the search results.
+	Position int
+}
+
+// parseFindPage returns the title candidates from the find page in the order of appearance.
+func parseFindPage(doc *html.Node) ([]Candidate, error) {
+	items, err := htmlquery.QueryAll(doc, `//li[`+xpathClass("find-title-result")+`]`)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(items))
+
+	for _, item := range items {
+		link, err := htmlquery.Query(item, `.//a[@class="ipc-metadata-list-summary-item__t"]`)
+		if err != nil || link == nil {
+			continue
+		}
+
+		id, ok := parseTitleHref(htmlquery.SelectAttr(link, "href"))
+		if !ok {
+			continue
+		}
+
+		candidate := Candidate{
+			ID:       id,
+			Title:    strings.TrimSpace(htmlquery.InnerText(link)),
+			Position: len(candidates) + 1,
+		}
+
+		meta, _ := htmlquery.Query(item, `.//ul[`+xpathClass("ipc-metadata-list-summary-item__tl")+`]`)
+		if meta != nil {
+			for _, li := range htmlquery.Find(meta, `.//span[@class="ipc-metadata-list-summary-item__li"]`) {
+				text := strings.TrimSpace(htmlquery.InnerText(li))
+
+				switch {
+				case candidate.Year == "" && candidateYearRx.MatchString(text):
+					candidate.Year = candidateYearRx.FindString(text)
+				case candidateEpisodeRx.MatchString(text):
+					continue
+				case candidate.Type == "":
+					candidate.Type = text
+				}
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// parseTitleHref returns the title ID from the "/title/tt0000000/..." link.
+func parseTitleHref(href string) (TitleID, bool) {
+	href, ok := strings.CutPrefix(href, "/title/")
+	if !ok {
+		return "", false
+	}
+
+	id := TitleID(strings.SplitN(href, "/", 2)[0])
+	if !id.IsValid() {
+		return "", false
+	}
+
+	return id, true
+}
+
+func xpathClass(class string) string {
+	return `contains(concat(" ", normalize-space(@class), " "), " ` + class + ` ")`
+}
@@ -2,95 +2,124 @@ package imdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/url"
 	"strings"
 
-	"github.com/antchfx/htmlquery"
-	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
-const (
-	findURL = Host + "/find/"
-)
+// ErrNotFound is returned by the DataLoader if it has no answer for the query.
+var ErrNotFound = errors.New("not found")
+
+// DataLoader resolves the IMDb title ID.
+type DataLoader interface {
+	GetID(ctx context.Context, query Query) (TitleID, Match, error)
+}
 
-func NewDataLoader(log *zap.Logger, downloader downloader.Downloader, cache Cache) DataLoader {
-	return &dataLoader{
-		log:        log,
-		downloader: downloader,
-		cache:      cache,
+func NewChainDataLoader(log *zap.Logger, cache Cache, links ...DataLoader) DataLoader {
+	return &chainDataLoader{
+		log:   log.With(zap.String("who", "imdb.chainDataLoader")),
+		cache: cache,
+		links: links,
 	}
 }
 
-type DataLoader interface {
-	GetIDByTitle(ctx context.Context, title string) (TitleID, error)
+// chainDataLoader asks the links one by one until one of them resolves the ID.
+// The answer of the link is stored in the cache unless it is the cache or override itself.
+// If all the links have not found the ID, the miss is stored in the cache.
+// The chain stops on the ErrSkipped, ErrCachedMiss and the caller's context errors,
+// the link's own request timeout is the link failure.
+// Identical queries requested concurrently are resolved once.
+type chainDataLoader struct {
+	log   *zap.Logger
+	cache Cache
+	links []DataLoader
+
+	group singleflight.Group
 }
 
-type dataLoader struct {
-	log        *zap.Logger
-	downloader downloader.Downloader
-	cache      Cache
+type matchResult struct {
+	id    TitleID
+	match Match
+	err   error
 }
 
-func (d *dataLoader) GetIDByTitle(ctx context.Context, title string) (TitleID, error) {
-	if err := ctx.Err(); err != nil {
-		return "", err
-	}
+func (d *chainDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	res, _, _ := d.group.Do(queryKey(query), func() (any, error) {
+		id, match, err := d.getID(ctx, query)
 
-	if cached, err := d.cache.GetTitleID(ctx, title); err == nil && cached != "" {
-		return cached, nil
-	}
+		return matchResult{id: id, match: match, err: err}, nil
+	})
 
-	query := url.Values{}
-	query.Set("s", "all")
-	query.Set("q", title)
+	result := res.(matchResult)
 
-	pageURL := findURL + "?" + query.Encode()
+	return result.id, result.match, result.err
+}
 
-	body, err := d.downloader.Download(ctx, pageURL)
-	if err != nil {
-		return "", err
-	}
+// queryKey returns the key of the query data all the links may resolve by.
+func queryKey(query Query) string {
+	return strings.Join(append([]string{query.KinopoiskURL, query.Title, query.Year}, query.Names...), "\x00")
+}
 
-	defer func() {
-		_ = body.Close()
-	}()
+func (d *chainDataLoader) getID(ctx context.Context, query Query) (TitleID, Match, error) {
+	var (
+		lastMatch  Match
+		errs       []error
+		isNotFound = true
+	)
 
-	doc, err := htmlquery.Parse(body)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse body: %w", err)
-	}
+	for _, link := range d.links {
+		if err := ctx.Err(); err != nil {
+			return "", lastMatch, err
+		}
 
-	item, err := htmlquery.Query(doc, `//a[@class="ipc-metadata-list-summary-item__t"]`)
-	if err != nil {
-		return "", fmt.Errorf("no item: %w", err)
-	}
+		id, match, err := link.GetID(ctx, query)
 
-	if item == nil {
-		return "", fmt.Errorf("no item")
-	}
+		if match.Source != "" {
+			lastMatch = match
+		}
 
-	href := htmlquery.SelectAttr(item, "href")
-	if href == "" {
-		return "", fmt.Errorf("no href")
-	}
+		if err == nil && id != "" {
+			if match.Source != SourceCache && match.Source != SourceOverride {
+				_ = d.cache.Store(ctx, NewCacheEntry(query, id, match))
+			}
+
+			return id, match, nil
+		}
+
+		if isChainStopError(ctx, err) {
+			return "", match, err
+		}
 
-	href, ok := strings.CutPrefix(href, "/title/")
-	if !ok {
-		return "", fmt.Errorf("no title href")
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			isNotFound = false
+
+			d.log.Debug(fmt.Sprintf("%s resolver failed: %s", match.Source, err), zap.String("title", query.Title))
+		}
+
+		if err != nil && match.Source != SourceOverride && match.Source != SourceCache {
+			errs = append(errs, fmt.Errorf("%s: %w", match.Source, err))
+		}
 	}
 
-	parts := strings.SplitN(href, "/", 2)
-	id := parts[0]
+	// The miss is stored only if some resolver was asked and nothing failed.
+	if isNotFound && len(errs) > 0 {
+		_ = d.cache.Store(ctx, NewCacheEntry(query, "", lastMatch))
+	}
 
-	if id == "" {
-		return "", fmt.Errorf("no id")
+	if len(errs) == 0 {
+		return "", lastMatch, ErrNotFound
 	}
 
-	tId := TitleID(id)
+	return "", lastMatch, errors.Join(errs...)
+}
 
-	_ = d.cache.StoreTitleID(ctx, title, tId)
+func isChainStopError(ctx context.Context, err error) bool {
+	if errors.Is(err, ErrSkipped) || errors.Is(err, ErrCachedMiss) {
+		return true
+	}
 
-	return tId, nil
+	return ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
 }
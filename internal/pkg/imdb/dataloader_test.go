@@ -0,0 +1,264 @@
+package imdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainDataLoader_GetID(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	overrides := imdb.Overrides{"474953": "tt1515091"}
+	overrides.Skip("4291715")
+
+	failing := &dataLoaderStub{source: "failing", err: errors.New("network is down")}
+	notFound := &dataLoaderStub{source: "empty", err: imdb.ErrNotFound}
+	found := &dataLoaderStub{source: "found", id: "tt17009710"}
+
+	loader := imdb.NewChainDataLoader(
+		log,
+		cache,
+		overrides,
+		imdb.NewCacheDataLoader(cache, false),
+		failing,
+		notFound,
+		found,
+	)
+
+	query := imdb.Query{Title: "Anatomie d'une chute (2023)", KinopoiskURL: "https://www.kinopoisk.ru/film/4910679/"}
+
+	id, match, err := loader.GetID(ctx, query)
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+	assert.Equal(t, imdb.Source("found"), match.Source)
+	assert.Equal(t, 1, found.calls)
+
+	id, match, err = loader.GetID(ctx, query)
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+	assert.Equal(t, imdb.SourceCache, match.Source)
+	assert.Equal(t, 1, found.calls)
+
+	id, match, err = loader.GetID(ctx, imdb.Query{Title: "Test", KinopoiskURL: "/film/474953/"})
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt1515091"), id)
+	assert.Equal(t, imdb.SourceOverride, match.Source)
+
+	_, _, err = loader.GetID(ctx, imdb.Query{Title: "Lightyear (2022)", KinopoiskURL: "/film/4291715/"})
+
+	assert.True(t, errors.Is(err, imdb.ErrSkipped))
+	assert.Equal(t, 1, found.calls)
+}
+
+func TestChainDataLoader_GetID_WhenNothingFound(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	failing := &dataLoaderStub{source: "failing", err: errors.New("network is down")}
+	notFound := &dataLoaderStub{source: "empty", err: imdb.ErrNotFound}
+
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	loader := imdb.NewChainDataLoader(log, cache, failing, notFound)
+
+	id, _, err := loader.GetID(context.Background(), imdb.Query{Title: "Test (2020)"})
+
+	assert.Empty(t, id)
+	assert.ErrorContains(t, err, "network is down")
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+}
+
+type dataLoaderStub struct {
+	source imdb.Source
+	id     imdb.TitleID
+	err    error
+	calls  int
+}
+
+func (d *dataLoaderStub) GetID(_ context.Context, _ imdb.Query) (imdb.TitleID, imdb.Match, error) {
+	d.calls++
+
+	if d.err != nil {
+		return "", imdb.Match{Source: d.source}, d.err
+	}
+
+	return d.id, imdb.Match{Source: d.source, Confidence: 1}, nil
+}
+
+func TestChainDataLoader_GetID_CachesMisses(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	notFound := &dataLoaderStub{source: "empty", err: imdb.ErrNotFound}
+	query := imdb.Query{Title: "Test (2020)"}
+
+	loader := imdb.NewChainDataLoader(log, cache, imdb.NewCacheDataLoader(cache, false), notFound)
+
+	_, _, err := loader.GetID(ctx, query)
+
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+	assert.False(t, errors.Is(err, imdb.ErrCachedMiss))
+	assert.Equal(t, 1, notFound.calls)
+
+	_, _, err = loader.GetID(ctx, query)
+
+	assert.True(t, errors.Is(err, imdb.ErrCachedMiss))
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+	assert.Equal(t, 1, notFound.calls)
+
+	retrying := imdb.NewChainDataLoader(log, cache, imdb.NewCacheDataLoader(cache, true), notFound)
+
+	_, _, err = retrying.GetID(ctx, query)
+
+	assert.False(t, errors.Is(err, imdb.ErrCachedMiss))
+	assert.Equal(t, 2, notFound.calls)
+}
+
+func TestChainDataLoader_GetID_WhenFailed_DoesNotCacheMiss(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	failing := &dataLoaderStub{source: "failing", err: errors.New("network is down")}
+
+	loader := imdb.NewChainDataLoader(log, cache, imdb.NewCacheDataLoader(cache, false), failing)
+
+	_, _, _ = loader.GetID(ctx, imdb.Query{Title: "Test (2020)"})
+
+	_, ok, err := cache.Get(ctx, imdb.Query{Title: "Test (2020)"})
+
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestChainDataLoader_GetID_WhenLinkTimedOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	found := &dataLoaderStub{source: "found", id: "tt17009710"}
+
+	loader := imdb.NewChainDataLoader(
+		log,
+		cache,
+		imdb.NewSuggestDataLoader(
+			log, downloader.NewStdDownloader(log, 50*time.Millisecond, nil), server.URL, imdb.DefaultMinConfidence,
+		),
+		found,
+	)
+
+	query := imdb.Query{Title: "Anatomie d'une chute (2023)", Names: []string{"Anatomie d'une chute"}, Year: "2023"}
+
+	id, match, err := loader.GetID(ctx, query)
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+	assert.Equal(t, imdb.Source("found"), match.Source)
+	assert.Equal(t, 1, found.calls)
+
+	// The caller's context timeout stops the chain.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err = loader.GetID(timeoutCtx, imdb.Query{Title: "Other (2020)", Names: []string{"Other"}, Year: "2020"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, found.calls)
+}
+
+func TestChainDataLoader_GetID_WhenRetriedLinkTimedOut(t *testing.T) {
+	requests := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	found := &dataLoaderStub{source: "found", id: "tt17009710"}
+
+	dwn := downloader.NewRetryDownloader(
+		log,
+		downloader.NewStdDownloader(log, 50*time.Millisecond, nil),
+		downloader.RetryOptions{Attempts: 4, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+	)
+
+	loader := imdb.NewChainDataLoader(
+		log,
+		cache,
+		imdb.NewSuggestDataLoader(log, dwn, server.URL, imdb.DefaultMinConfidence),
+		found,
+	)
+
+	start := time.Now()
+
+	id, _, err := loader.GetID(context.Background(), imdb.Query{
+		Title: "Anatomie d'une chute (2023)",
+		Names: []string{"Anatomie d'une chute"},
+		Year:  "2023",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+
+	// The timed out request is not retried, the next link is asked instead.
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// blockingDataLoaderStub counts the calls and answers when the release channel is closed.
+type blockingDataLoaderStub struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (d *blockingDataLoaderStub) GetID(_ context.Context, _ imdb.Query) (imdb.TitleID, imdb.Match, error) {
+	d.calls.Add(1)
+	<-d.release
+
+	return "tt17009710", imdb.Match{Source: "blocking", Confidence: 1}, nil
+}
+
+func TestChainDataLoader_GetID_Concurrent(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	cache := imdb.NewMemoryCache(log, imdb.DefaultMissTTL, imdb.ConflictPolicyNewest)
+	stub := &blockingDataLoaderStub{release: make(chan struct{})}
+	loader := imdb.NewChainDataLoader(log, cache, imdb.NewCacheDataLoader(cache, false), stub)
+
+	query := imdb.Query{Title: "Anatomie d'une chute (2023)", KinopoiskURL: "/film/4910679/"}
+	results := make(chan imdb.TitleID, 3)
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			id, _, _ := loader.GetID(context.Background(), query)
+			results <- id
+		}()
+	}
+
+	require.Eventually(t, func() bool { return stub.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(stub.release)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, imdb.TitleID("tt17009710"), <-results)
+	}
+
+	assert.Equal(t, int32(1), stub.calls.Load())
+}
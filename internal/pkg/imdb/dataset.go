@@ -0,0 +1,500 @@
+package imdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+const (
+	DatasetBasicsFile = "title.basics.tsv.gz"
+	DatasetAkasFile   = "title.akas.tsv.gz"
+
+	datasetIndexFile = "title.index.db"
+	datasetNull      = `\N`
+
+	// datasetBatchSize is a number of the rows written to the index in one transaction.
+	datasetBatchSize = 100000
+)
+
+var datasetTitlesBucket = []byte("titles")
+
+// datasetTitleTypes are the dataset title types to index mapped to the find page type names.
+// Episodes, games, etc. are not indexed.
+var datasetTitleTypes = map[string]string{
+	"movie":        "",
+	"tvMovie":      "TV Movie",
+	"tvSeries":     "TV Series",
+	"tvMiniSeries": "TV Mini Series",
+	"tvSpecial":    "TV Special",
+	"short":        "Short",
+	"tvShort":      "TV Short",
+	"video":        "Video",
+}
+
+// Dataset is an on-disk index of the titles from the IMDb public datasets
+// (https://developer.imdb.com/non-commercial-datasets/), keyed by the normalized title.
+type Dataset struct {
+	log *zap.Logger
+	db  *bolt.DB
+}
+
+// OpenDataset opens the dataset index in the dir.
+// The index is (re)built from the title.basics.tsv.gz and the optional title.akas.tsv.gz files
+// if it doesn't exist or is older than them.
+func OpenDataset(ctx context.Context, log *zap.Logger, dir string) (*Dataset, error) {
+	log = log.With(zap.String("who", "imdb.Dataset"), zap.String("dir", dir))
+
+	basicsPath := filepath.Join(dir, DatasetBasicsFile)
+	akasPath := filepath.Join(dir, DatasetAkasFile)
+	indexPath := filepath.Join(dir, datasetIndexFile)
+
+	basicsStat, err := os.Stat(basicsPath)
+	if err != nil {
+		return nil, fmt.Errorf("no IMDb dataset file %s: %w", basicsPath, err)
+	}
+
+	sourceTime := basicsStat.ModTime()
+
+	akasStat, err := os.Stat(akasPath)
+	if err == nil {
+		if akasStat.ModTime().After(sourceTime) {
+			sourceTime = akasStat.ModTime()
+		}
+	} else {
+		akasPath = ""
+	}
+
+	if indexStat, err := os.Stat(indexPath); err != nil || indexStat.ModTime().Before(sourceTime) {
+		if err := buildDatasetIndex(ctx, log, indexPath, basicsPath, akasPath); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(indexPath, 0644, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IMDb dataset index %s: %w", indexPath, err)
+	}
+
+	return &Dataset{log: log, db: db}, nil
+}
+
+// Find returns the candidates having one of the names as a primary, original or alternative title.
+func (d *Dataset) Find(names []string) ([]Candidate, error) {
+	candidates := make([]Candidate, 0)
+	seen := make(map[TitleID]struct{})
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(datasetTitlesBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		for _, name := range names {
+			key := NormalizeTitle(name)
+			if key == "" {
+				continue
+			}
+
+			for _, entry := range splitDatasetEntries(bucket.Get([]byte(key))) {
+				candidate, ok := parseDatasetEntry(entry)
+				if !ok {
+					continue
+				}
+
+				if _, ok := seen[candidate.ID]; ok {
+					continue
+				}
+
+				seen[candidate.ID] = struct{}{}
+				candidates = append(candidates, candidate)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IMDb dataset index: %w", err)
+	}
+
+	return candidates, nil
+}
+
+func (d *Dataset) Close() error {
+	return d.db.Close()
+}
+
+type datasetTitle struct {
+	id   string
+	year string
+	kind string
+}
+
+func (t datasetTitle) entry(title string) string {
+	return t.id + "\t" + t.year + "\t" + t.kind + "\t" + title
+}
+
+func buildDatasetIndex(ctx context.Context, log *zap.Logger, indexPath, basicsPath, akasPath string) error {
+	log.Info("Building IMDb dataset index, it may take a while")
+
+	tmpPath := indexPath + ".tmp"
+	_ = os.Remove(tmpPath)
+
+	db, err := bolt.Open(tmpPath, 0644, &bolt.Options{NoSync: true})
+	if err != nil {
+		return fmt.Errorf("failed to create IMDb dataset index %s: %w", tmpPath, err)
+	}
+
+	var titles *datasetTitles
+
+	// The titles are kept on disk to match the akas by the ID, as all of them don't fit the memory.
+	if akasPath != "" {
+		titles, err = openDatasetTitles(indexPath + ".titles.tmp")
+		if err != nil {
+			_ = db.Close()
+			_ = os.Remove(tmpPath)
+
+			return err
+		}
+
+		defer titles.close()
+	}
+
+	count := 0
+	batch := newDatasetBatch(db)
+
+	err = readDatasetTSV(ctx, basicsPath, func(row []string) error {
+		// tconst, titleType, primaryTitle, originalTitle, isAdult, startYear, ...
+		if len(row) < 6 {
+			return nil
+		}
+
+		kind, ok := datasetTitleTypes[row[1]]
+		if !ok {
+			return nil
+		}
+
+		title := datasetTitle{id: row[0], kind: kind}
+		if row[5] != datasetNull {
+			title.year = row[5]
+		}
+
+		count++
+
+		batch.add(row[2], title.entry(row[2]))
+
+		if row[3] != row[2] {
+			batch.add(row[3], title.entry(row[3]))
+		}
+
+		if titles != nil {
+			if err := titles.add(title); err != nil {
+				return err
+			}
+		}
+
+		return batch.flushIfFull()
+	})
+
+	if err == nil && titles != nil {
+		err = titles.flush()
+	}
+
+	if err == nil && akasPath != "" {
+		akas := make([][2]string, 0, datasetBatchSize)
+
+		addAkas := func() error {
+			found, err := titles.find(akas)
+			if err != nil {
+				return err
+			}
+
+			for i, aka := range akas {
+				if title, ok := found[i]; ok {
+					batch.add(aka[1], title.entry(aka[1]))
+				}
+			}
+
+			akas = akas[:0]
+
+			return batch.flushIfFull()
+		}
+
+		err = readDatasetTSV(ctx, akasPath, func(row []string) error {
+			// titleId, ordering, title, region, ...
+			if len(row) < 3 {
+				return nil
+			}
+
+			akas = append(akas, [2]string{row[0], row[2]})
+
+			if len(akas) < datasetBatchSize {
+				return nil
+			}
+
+			return addAkas()
+		})
+
+		if err == nil {
+			err = addAkas()
+		}
+	}
+
+	if err == nil {
+		err = batch.flush()
+	}
+
+	if err == nil {
+		err = db.Sync()
+	}
+
+	closeErr := db.Close()
+
+	if err == nil && closeErr != nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return fmt.Errorf("failed to build IMDb dataset index: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, indexPath); err != nil {
+		return fmt.Errorf("failed to move IMDb dataset index to %s: %w", indexPath, err)
+	}
+
+	log.Info(fmt.Sprintf("IMDb dataset index built, %d title(s) indexed", count))
+
+	return nil
+}
+
+func readDatasetTSV(ctx context.Context, sourcePath string, handle func(row []string) error) error {
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", sourcePath, err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip %s: %w", sourcePath, err)
+	}
+
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	reader := bufio.NewReaderSize(gz, 1024*1024)
+	isHeader := true
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read %s: %w", sourcePath, err)
+		}
+
+		if line = strings.TrimRight(line, "\r\n"); line != "" && !isHeader {
+			if err := handle(strings.Split(line, "\t")); err != nil {
+				return err
+			}
+		}
+
+		isHeader = false
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// datasetBatch collects the index entries and writes them to the db in one transaction.
+type datasetBatch struct {
+	db      *bolt.DB
+	entries map[string][]string
+	rows    int
+}
+
+func newDatasetBatch(db *bolt.DB) *datasetBatch {
+	return &datasetBatch{db: db, entries: make(map[string][]string)}
+}
+
+func (b *datasetBatch) add(title string, entry string) {
+	key := NormalizeTitle(title)
+	if key == "" {
+		return
+	}
+
+	b.entries[key] = append(b.entries[key], entry)
+	b.rows++
+}
+
+func (b *datasetBatch) flushIfFull() error {
+	if b.rows < datasetBatchSize {
+		return nil
+	}
+
+	return b.flush()
+}
+
+func (b *datasetBatch) flush() error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(datasetTitlesBucket)
+		if err != nil {
+			return err
+		}
+
+		for key, entries := range b.entries {
+			existing := splitDatasetEntries(bucket.Get([]byte(key)))
+
+			if err := bucket.Put([]byte(key), joinDatasetEntries(existing, entries)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	b.entries = make(map[string][]string)
+	b.rows = 0
+
+	return err
+}
+
+// datasetTitles is a temporary on-disk store of the indexed titles by the ID, written in batches.
+type datasetTitles struct {
+	path    string
+	db      *bolt.DB
+	pending map[string]string
+}
+
+func openDatasetTitles(path string) (*datasetTitles, error) {
+	_ = os.Remove(path)
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{NoSync: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IMDb dataset titles file %s: %w", path, err)
+	}
+
+	return &datasetTitles{path: path, db: db, pending: make(map[string]string)}, nil
+}
+
+func (t *datasetTitles) add(title datasetTitle) error {
+	t.pending[title.id] = title.year + "\t" + title.kind
+
+	if len(t.pending) < datasetBatchSize {
+		return nil
+	}
+
+	return t.flush()
+}
+
+func (t *datasetTitles) flush() error {
+	err := t.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(datasetTitlesBucket)
+		if err != nil {
+			return err
+		}
+
+		for id, value := range t.pending {
+			if err := bucket.Put([]byte(id), []byte(value)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	t.pending = make(map[string]string)
+
+	return err
+}
+
+// find returns the titles of the akas (the ID and title pairs) by the akas indexes, the unknown IDs are skipped.
+func (t *datasetTitles) find(akas [][2]string) (map[int]datasetTitle, error) {
+	found := make(map[int]datasetTitle, len(akas))
+
+	err := t.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(datasetTitlesBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		for i, aka := range akas {
+			value := bucket.Get([]byte(aka[0]))
+			if value == nil {
+				continue
+			}
+
+			year, kind, _ := strings.Cut(string(value), "\t")
+			found[i] = datasetTitle{id: aka[0], year: year, kind: kind}
+		}
+
+		return nil
+	})
+
+	return found, err
+}
+
+func (t *datasetTitles) close() {
+	_ = t.db.Close()
+	_ = os.Remove(t.path)
+}
+
+func splitDatasetEntries(value []byte) []string {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return strings.Split(string(value), "\n")
+}
+
+// joinDatasetEntries joins the entries skipping the ones with already added IDs.
+func joinDatasetEntries(existing []string, added []string) []byte {
+	ids := make(map[string]struct{}, len(existing)+len(added))
+	result := make([]string, 0, len(existing)+len(added))
+
+	for _, entry := range append(existing, added...) {
+		id, _, _ := strings.Cut(entry, "\t")
+
+		if _, ok := ids[id]; ok {
+			continue
+		}
+
+		ids[id] = struct{}{}
+		result = append(result, entry)
+	}
+
+	return []byte(strings.Join(result, "\n"))
+}
+
+func parseDatasetEntry(entry string) (Candidate, bool) {
+	parts := strings.SplitN(entry, "\t", 4)
+	if len(parts) != 4 {
+		return Candidate{}, false
+	}
+
+	return Candidate{
+		ID:    TitleID(parts[0]),
+		Year:  parts[1],
+		Type:  parts[2],
+		Title: parts[3],
+	}, true
+}
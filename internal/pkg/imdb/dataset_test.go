@@ -0,0 +1,96 @@
+package imdb_test
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBasicsTSV = "tconst\ttitleType\tprimaryTitle\toriginalTitle\tisAdult\tstartYear\tendYear\truntimeMinutes\tgenres\n" +
+	"tt17009710\tmovie\tAnatomy of a Fall\tAnatomie d'une chute\t0\t2023\t\\N\t151\tCrime,Drama,Thriller\n" +
+	"tt0000001\tmovie\tAnatomy of a Fall\tAnatomy of a Fall\t0\t1990\t\\N\t90\tDrama\n" +
+	"tt0000002\ttvEpisode\tAnatomy of a Fall\tAnatomy of a Fall\t0\t2023\t\\N\t30\tDrama\n" +
+	"tt0000003\tmovie\tHamlet\tHamlet\t0\t1990\t\\N\t135\tDrama\n" +
+	"tt0000004\tmovie\tHamlet\tHamlet\t0\t1990\t\\N\t120\tDrama\n"
+
+const testAkasTSV = "titleId\tordering\ttitle\tregion\tlanguage\ttypes\tattributes\tisOriginalTitle\n" +
+	"tt17009710\t1\tАнатомия падения\tRU\t\\N\timdbDisplay\t\\N\t0\n" +
+	"tt0000002\t1\tАнатомия падения\tRU\t\\N\timdbDisplay\t\\N\t0\n"
+
+func TestDatasetDataLoader_GetID(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	dir := t.TempDir()
+
+	writeGzip(t, filepath.Join(dir, imdb.DatasetBasicsFile), testBasicsTSV)
+	writeGzip(t, filepath.Join(dir, imdb.DatasetAkasFile), testAkasTSV)
+
+	dataset, err := imdb.OpenDataset(ctx, log, dir)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = dataset.Close()
+	})
+
+	loader := imdb.NewDatasetDataLoader(log, dataset, imdb.DefaultMinConfidence)
+
+	tests := []struct {
+		Query      imdb.Query
+		ExpectedID imdb.TitleID
+	}{
+		{imdb.Query{Title: "Anatomie d'une chute (2023)", Names: []string{"Anatomie d'une chute"}, Year: "2023"}, "tt17009710"},
+		{imdb.Query{Title: "Анатомия падения (2023)", Names: []string{"Анатомия падения"}, Year: "2023"}, "tt17009710"},
+		{imdb.Query{Title: "Anatomy of a Fall (1990)", Names: []string{"Anatomy of a Fall"}, Year: "1990"}, "tt0000001"},
+		{imdb.Query{Title: "Anatomy of a Fall (1970)", Names: []string{"Anatomy of a Fall"}, Year: "1970"}, ""},
+		{imdb.Query{Title: "Unknown (2023)", Names: []string{"Unknown"}, Year: "2023"}, ""},
+	}
+
+	for i, test := range tests {
+		id, match, err := loader.GetID(ctx, test.Query)
+
+		assert.Equal(t, test.ExpectedID, id, i)
+		assert.Equal(t, imdb.SourceDataset, match.Source, i)
+
+		if test.ExpectedID == "" {
+			assert.Error(t, err, i)
+		} else {
+			assert.NoError(t, err, i)
+		}
+	}
+
+	// The exact name and year match is not reviewed, as the dataset titles have no search position.
+	_, match, err := loader.GetID(ctx, imdb.Query{
+		Title: "Anatomy of a Fall (1990)",
+		Names: []string{"Anatomy of a Fall"},
+		Year:  "1990",
+	})
+	require.NoError(t, err)
+	assert.False(t, match.IsLowConfidence(0.75))
+
+	// The titles of the same name and year are ambiguous, the match is written to the review report.
+	id, match, err := loader.GetID(ctx, imdb.Query{Title: "Hamlet (1990)", Names: []string{"Hamlet"}, Year: "1990"})
+	require.NoError(t, err)
+	assert.Contains(t, []imdb.TitleID{"tt0000003", "tt0000004"}, id)
+	assert.GreaterOrEqual(t, match.Confidence, imdb.DefaultMinConfidence)
+	assert.True(t, match.IsLowConfidence(0.75))
+}
+
+func writeGzip(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	gz := gzip.NewWriter(f)
+
+	_, err = gz.Write([]byte(content))
+	require.NoError(t, err)
+
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+}
@@ -0,0 +1,97 @@
+package imdb
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// datasetYearBaseScore is a score of the dataset title matching by the name, but not by the year.
+	datasetYearBaseScore = 0.4
+	// datasetAmbiguityFactor lowers the score of the best dataset title if the other ones score the same,
+	// so the perfect, but ambiguous match is below the review confidence.
+	datasetAmbiguityFactor = 0.6
+)
+
+func NewDatasetDataLoader(log *zap.Logger, dataset *Dataset, minConfidence float64) DataLoader {
+	return &datasetDataLoader{
+		log:           log.With(zap.String("who", "imdb.datasetDataLoader")),
+		dataset:       dataset,
+		minConfidence: minConfidence,
+	}
+}
+
+// datasetDataLoader resolves the IMDb IDs offline using the IMDb dataset index.
+type datasetDataLoader struct {
+	log           *zap.Logger
+	dataset       *Dataset
+	minConfidence float64
+}
+
+func (d *datasetDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Match{}, err
+	}
+
+	match := Match{Source: SourceDataset}
+
+	names := query.Names
+	if len(names) == 0 {
+		names = []string{query.Title}
+	}
+
+	candidates, err := d.dataset.Find(names)
+	if err != nil {
+		return "", match, err
+	}
+
+	if len(candidates) == 0 {
+		return "", match, fmt.Errorf("no item in dataset: %w", ErrNotFound)
+	}
+
+	best, score := pickBestDatasetCandidate(query, candidates)
+
+	match.Confidence = score
+	match.Candidates = len(candidates)
+
+	if score < d.minConfidence {
+		return "", match, fmt.Errorf(
+			"no confident match in dataset, best is %s '%s' (%s) with score %.2f: %w",
+			best.ID.String(), best.Title, best.Year, score, ErrNotFound,
+		)
+	}
+
+	return best.ID, match, nil
+}
+
+// pickBestDatasetCandidate returns the best scored dataset title.
+// The dataset titles have no search position, so they are scored by the name and year only.
+func pickBestDatasetCandidate(query Query, candidates []Candidate) (best Candidate, score float64) {
+	scores := make([]float64, len(candidates))
+	score = -1
+
+	for i, candidate := range candidates {
+		scores[i] = scoreDatasetCandidate(query, candidate)
+
+		if scores[i] > score {
+			best, score = candidate, scores[i]
+		}
+	}
+
+	for i, candidate := range candidates {
+		if candidate.ID != best.ID && score-scores[i] < 1e-9 {
+			return best, score * datasetAmbiguityFactor
+		}
+	}
+
+	return best, score
+}
+
+func scoreDatasetCandidate(query Query, candidate Candidate) float64 {
+	year := yearScore(query.Year, candidate.Year)
+
+	return titleSimilarity(query, candidate) * (datasetYearBaseScore + (1-datasetYearBaseScore)*year) *
+		typeFactor(candidate)
+}
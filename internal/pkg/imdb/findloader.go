@@ -0,0 +1,107 @@
+package imdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"go.uber.org/zap"
+)
+
+const (
+	findURL = Host + "/find/"
+)
+
+func NewFindDataLoader(log *zap.Logger, downloader downloader.Downloader, minConfidence float64) DataLoader {
+	return &findDataLoader{
+		log:           log.With(zap.String("who", "imdb.findDataLoader")),
+		downloader:    downloader,
+		minConfidence: minConfidence,
+	}
+}
+
+// findDataLoader resolves the IMDb IDs using the imdb.com search page.
+type findDataLoader struct {
+	log           *zap.Logger
+	downloader    downloader.Downloader
+	minConfidence float64
+}
+
+func (d *findDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Match{}, err
+	}
+
+	return d.getIDByTitle(ctx, query)
+}
+
+func (d *findDataLoader) getIDByTitle(ctx context.Context, query Query) (TitleID, Match, error) {
+	title := query.Title
+	match := Match{Source: SourceFind}
+
+	values := url.Values{}
+	values.Set("s", "all")
+	values.Set("q", title)
+
+	pageURL := findURL + "?" + values.Encode()
+
+	body, err := d.downloader.Download(ctx, pageURL)
+	if err != nil {
+		return "", match, err
+	}
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	doc, err := htmlquery.Parse(body)
+	if err != nil {
+		return "", match, fmt.Errorf("failed to parse body: %w", err)
+	}
+
+	candidates, err := parseFindPage(doc)
+	if err != nil {
+		return "", match, fmt.Errorf("failed to parse candidates: %w", err)
+	}
+
+	if len(candidates) == 0 {
+		return "", match, fmt.Errorf("no item: %w", ErrNotFound)
+	}
+
+	best, score := pickBestCandidate(query, candidates)
+
+	match.Confidence = score
+	match.Candidates = len(candidates)
+
+	d.log.Debug(
+		"Best candidate picked",
+		zap.String("title", title),
+		zap.String("candidate", best.Title),
+		zap.String("id", best.ID.String()),
+		zap.Float64("score", score),
+		zap.Int("candidates", len(candidates)),
+	)
+
+	if score < d.minConfidence {
+		return "", match, fmt.Errorf(
+			"no confident match, best is %s '%s' (%s) with score %.2f: %w",
+			best.ID.String(), best.Title, best.Year, score, ErrNotFound,
+		)
+	}
+
+	return best.ID, match, nil
+}
+
+func pickBestCandidate(query Query, candidates []Candidate) (best Candidate, score float64) {
+	score = -1
+
+	for _, candidate := range candidates {
+		if curr := ScoreCandidate(query, candidate); curr > score {
+			best, score = candidate, curr
+		}
+	}
+
+	return best, score
+}
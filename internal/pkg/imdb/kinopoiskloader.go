@@ -0,0 +1,220 @@
+package imdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/antchfx/htmlquery"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk/blockpage"
+	"go.uber.org/zap"
+)
+
+// kinopoiskPageMaxSize is a max number of bytes read from the kinopoisk film page.
+const kinopoiskPageMaxSize = 10 * 1024 * 1024
+
+// kinopoiskPageIDRx matches the IMDb ID in the page's structured data,
+// the page has the data of the related films too, so the object the ID belongs to must be checked.
+var kinopoiskPageIDRx = regexp.MustCompile(`"imdb_?[iI]d"\s*:\s*"(tt[0-9]+)"`)
+
+// kinopoiskPageFilmIDKeys are the keys of the kinopoisk film ID in the page's structured data objects.
+var kinopoiskPageFilmIDKeys = []string{"id", "filmId", "kinopoiskId", "kinopoisk_id"}
+
+// kinopoiskPageJSON keeps the numeric IDs as they are written to be compared with the film ID.
+var kinopoiskPageJSON = jsoniter.Config{UseNumber: true}.Froze()
+
+// kinopoiskPageLinkXPath matches the IMDb link in the film's rating block,
+// the links to the other films (sequels, similar films) are not taken into account.
+const kinopoiskPageLinkXPath = `//*[contains(@class, "film-rating") or contains(@class, "filmRating")]` +
+	`//a[contains(@href, "imdb.com/title/")]`
+
+// kinopoiskPageLinkConfidence is a confidence of the ID found by the link only.
+const kinopoiskPageLinkConfidence = 0.8
+
+var kinopoiskPageLinkIDRx = regexp.MustCompile(`imdb\.com/title/(tt[0-9]+)`)
+
+func NewKinopoiskPageDataLoader(log *zap.Logger, downloader downloader.Downloader) DataLoader {
+	return &kinopoiskPageDataLoader{
+		log:        log.With(zap.String("who", "imdb.kinopoiskPageDataLoader")),
+		downloader: downloader,
+	}
+}
+
+// kinopoiskPageDataLoader looks for the IMDb ID on the kinopoisk film page.
+type kinopoiskPageDataLoader struct {
+	log        *zap.Logger
+	downloader downloader.Downloader
+}
+
+func (d *kinopoiskPageDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Match{}, err
+	}
+
+	match := Match{Source: SourceKinopoisk}
+
+	if query.KinopoiskURL == "" {
+		return "", match, fmt.Errorf("no kinopoisk URL: %w", ErrNotFound)
+	}
+
+	id, confidence, err := d.getIDFromPage(ctx, query.KinopoiskURL, query.KinopoiskFilmID())
+	if err != nil {
+		return "", match, fmt.Errorf("failed to get IMDb ID from the kinopoisk page: %w", err)
+	}
+
+	if id == "" {
+		return "", match, fmt.Errorf("no IMDb ID on the kinopoisk page: %w", ErrNotFound)
+	}
+
+	match.Confidence = confidence
+	match.Candidates = 1
+
+	return id, match, nil
+}
+
+func (d *kinopoiskPageDataLoader) getIDFromPage(
+	ctx context.Context,
+	pageURL string,
+	filmID string,
+) (TitleID, float64, error) {
+	body, err := d.downloader.Download(ctx, pageURL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	content, err := io.ReadAll(io.LimitReader(body, kinopoiskPageMaxSize))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	id, confidence := FindTitleIDInPage(content, filmID)
+	if id != "" {
+		return id, confidence, nil
+	}
+
+	// The captcha or login page has no ID too, but it must not be taken for the film without the ID.
+	if doc, err := htmlquery.Parse(bytes.NewReader(content)); err == nil {
+		if err := blockpage.Detect(doc); err != nil {
+			return "", 0, err
+		}
+	}
+
+	return "", 0, nil
+}
+
+// FindTitleIDInPage returns the film's IMDb title ID from the page's content and the confidence of it.
+// The ID from the structured data object of the film with the filmID kinopoisk ID is preferred,
+// the IMDb link in the film's rating block is a fallback.
+func FindTitleIDInPage(content []byte, filmID string) (TitleID, float64) {
+	if id := findTitleIDInPageData(content, filmID); id != "" {
+		return id, 1
+	}
+
+	doc, err := htmlquery.Parse(bytes.NewReader(content))
+	if err != nil {
+		return "", 0
+	}
+
+	link, err := htmlquery.Query(doc, kinopoiskPageLinkXPath)
+	if err != nil || link == nil {
+		return "", 0
+	}
+
+	if match := kinopoiskPageLinkIDRx.FindStringSubmatch(htmlquery.SelectAttr(link, "href")); match != nil {
+		return TitleID(match[1]), kinopoiskPageLinkConfidence
+	}
+
+	return "", 0
+}
+
+// findTitleIDInPageData returns the IMDb ID of the structured data object having the filmID kinopoisk ID.
+func findTitleIDInPageData(content []byte, filmID string) TitleID {
+	if filmID == "" {
+		return ""
+	}
+
+	for _, match := range kinopoiskPageIDRx.FindAllSubmatchIndex(content, -1) {
+		object := enclosingJSONObject(content, match[0])
+		if object == nil {
+			continue
+		}
+
+		fields := make(map[string]any)
+		if err := kinopoiskPageJSON.Unmarshal(object, &fields); err != nil {
+			continue
+		}
+
+		for _, key := range kinopoiskPageFilmIDKeys {
+			if val, ok := fields[key]; ok && fmt.Sprint(val) == filmID {
+				return TitleID(content[match[2]:match[3]])
+			}
+		}
+	}
+
+	return ""
+}
+
+// enclosingJSONObject returns the innermost JSON object of the content containing the pos, nil if there is none.
+func enclosingJSONObject(content []byte, pos int) []byte {
+	start := -1
+	depth := 0
+	inString := false
+
+	for i := pos - 1; i >= 0 && start < 0; i-- {
+		switch {
+		case content[i] == '"' && !isEscaped(content, i):
+			inString = !inString
+		case inString:
+		case content[i] == '}':
+			depth++
+		case content[i] == '{' && depth == 0:
+			start = i
+		case content[i] == '{':
+			depth--
+		}
+	}
+
+	if start < 0 {
+		return nil
+	}
+
+	depth = 0
+	inString = false
+
+	for i := start; i < len(content); i++ {
+		switch {
+		case content[i] == '"' && !isEscaped(content, i):
+			inString = !inString
+		case inString:
+		case content[i] == '{':
+			depth++
+		case content[i] == '}':
+			depth--
+
+			if depth == 0 {
+				return content[start : i+1]
+			}
+		}
+	}
+
+	return nil
+}
+
+// isEscaped returns true if the content's char at the pos is escaped by the odd number of backslashes.
+func isEscaped(content []byte, pos int) bool {
+	n := 0
+
+	for i := pos - 1; i >= 0 && content[i] == '\\'; i-- {
+		n++
+	}
+
+	return n%2 == 1
+}
@@ -0,0 +1,115 @@
+package imdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/kinopoisk/blockpage"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKinopoiskPageDataLoader_GetID(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := downloader.NewDownloaderFileMock(map[string]string{
+		"https://www.kinopoisk.ru/film/4910679/": "./testdata/kinopoisk_film.html",
+		"https://www.kinopoisk.ru/film/4291715/": "./testdata/kinopoisk_film_no_imdb.html",
+		"https://www.kinopoisk.ru/film/4910680/": "./testdata/kinopoisk_film_related.html",
+		"https://www.kinopoisk.ru/film/4910681/": "./testdata/kinopoisk_captcha.html",
+	})
+	loader := imdb.NewKinopoiskPageDataLoader(log, dwn)
+
+	id, match, err := loader.GetID(ctx, imdb.Query{
+		Title:        "Anatomie d'une chute (2023)",
+		KinopoiskURL: "https://www.kinopoisk.ru/film/4910679/",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+	assert.Equal(t, imdb.SourceKinopoisk, match.Source)
+
+	id, match, err = loader.GetID(ctx, imdb.Query{
+		Title:        "Anatomie d'une chute (2023)",
+		KinopoiskURL: "https://www.kinopoisk.ru/film/4910680/",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+	assert.Less(t, match.Confidence, float64(1))
+
+	id, _, err = loader.GetID(ctx, imdb.Query{
+		Title:        "Lightyear (2022)",
+		KinopoiskURL: "https://www.kinopoisk.ru/film/4291715/",
+	})
+
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+	assert.Empty(t, id)
+
+	// The captcha page is the loader's failure, not the film without the ID, so it is not cached as a miss.
+	id, _, err = loader.GetID(ctx, imdb.Query{
+		Title:        "Anatomie d'une chute (2023)",
+		KinopoiskURL: "https://www.kinopoisk.ru/film/4910681/",
+	})
+
+	assert.ErrorIs(t, err, blockpage.ErrCaptcha)
+	assert.False(t, errors.Is(err, imdb.ErrNotFound))
+	assert.Empty(t, id)
+}
+
+func TestFindTitleIDInPage(t *testing.T) {
+	tests := []struct {
+		FilmID             string
+		Content            string
+		ExpectedID         imdb.TitleID
+		ExpectedConfidence float64
+	}{
+		{
+			FilmID: "4910679",
+			Content: `<a href="https://www.imdb.com/title/tt0052561/">IMDb</a>` +
+				`<script type="application/json">{"film":{"id":4910679,"imdbId":"tt17009710"}}</script>`,
+			ExpectedID:         "tt17009710",
+			ExpectedConfidence: 1,
+		},
+		{
+			FilmID: "4910679",
+			Content: `<script type="application/json">{"sequels":[{"id":1,"imdbId":"tt0052561"}],` +
+				`"film":{"genre":{"id":2,"name":"drama \"{"},"imdbId":"tt17009710","id":"4910679"}}</script>`,
+			ExpectedID:         "tt17009710",
+			ExpectedConfidence: 1,
+		},
+		{
+			FilmID: "4910679",
+			Content: `<script type="application/json">{"sequels":[{"id":1,"imdbId":"tt0052561"}]}</script>` +
+				`<div class="film-rating"><a href="https://www.imdb.com/title/tt17009710/">IMDb</a></div>`,
+			ExpectedID:         "tt17009710",
+			ExpectedConfidence: 0.8,
+		},
+		{
+			Content:    `<script type="application/json">{"film":{"imdbId":"tt17009710"}}</script>`,
+			ExpectedID: "",
+		},
+		{
+			Content: `<div class="styles_filmRating__x">` +
+				`<a href="https://www.imdb.com/title/tt17009710/">IMDb</a></div>`,
+			ExpectedID:         "tt17009710",
+			ExpectedConfidence: 0.8,
+		},
+		{
+			Content: `<div class="film-sequels"><a href="https://www.imdb.com/title/tt0052561/">IMDb</a></div>`,
+		},
+		{
+			Content: `<html></html>`,
+		},
+	}
+
+	for i, test := range tests {
+		id, confidence := imdb.FindTitleIDInPage([]byte(test.Content), test.FilmID)
+
+		assert.Equal(t, test.ExpectedID, id, i)
+		assert.Equal(t, test.ExpectedConfidence, confidence, i)
+	}
+}
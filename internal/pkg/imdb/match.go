@@ -0,0 +1,31 @@
+package imdb
+
+// Source is a name of the IMDb title ID source.
+type Source string
+
+const (
+	SourceOverride  Source = "override"
+	SourceCache     Source = "cache"
+	SourceFind      Source = "find"
+	SourceDataset   Source = "dataset"
+	SourceKinopoisk Source = "kinopoisk"
+	SourceWikidata  Source = "wikidata"
+	SourceSuggest   Source = "suggest"
+)
+
+// Match describes how the IMDb title ID was resolved.
+type Match struct {
+	Source Source
+
+	// Confidence is a score from 0 to 1, zero if unknown.
+	Confidence float64
+
+	// Candidates is a number of the candidates the ID was chosen from, zero if unknown.
+	Candidates int
+}
+
+// IsLowConfidence returns true if the confidence is known and below the min one.
+// The matches of unknown confidence, like the legacy cache entries, are not low.
+func (m Match) IsLowConfidence(min float64) bool {
+	return m.Confidence > 0 && m.Confidence < min
+}
@@ -0,0 +1,274 @@
+package imdb
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const overrideSkip = "skip"
+
+// ErrSkipped is returned when the title is marked to be skipped in the overrides.
+var ErrSkipped = errors.New("skipped by override")
+
+// Overrides are the manual IMDb IDs set for the kinopoisk films, keyed by the kinopoisk film ID.
+type Overrides map[string]TitleID
+
+// LoadOverrides reads the overrides from the CSV file.
+// Each row contains the kinopoisk film URL or ID and the IMDb title ID or "skip".
+// The rows in an unknown format are skipped with a warning, except the header on the first line.
+func LoadOverrides(log *zap.Logger, sourcePath string) (Overrides, error) {
+	log = log.With(zap.String("who", "imdb.LoadOverrides"), zap.String("path", sourcePath))
+
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open overrides file %s: %w", sourcePath, err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	overrides := make(Overrides)
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overrides file %s: %w", sourcePath, err)
+		}
+
+		filmID, id, err := parseOverridesRow(row)
+		if err != nil {
+			if line, _ := reader.FieldPos(0); line > 1 {
+				log.Warn(fmt.Sprintf("Override on line %d is skipped: %s", line, err.Error()))
+			}
+
+			continue
+		}
+
+		overrides[filmID] = id
+	}
+
+	return overrides, nil
+}
+
+// parseOverridesRow returns the kinopoisk film ID and the IMDb ID (or "skip") of the overrides row.
+func parseOverridesRow(row []string) (filmID string, id TitleID, err error) {
+	if len(row) < 2 {
+		return "", "", errors.New("expected the kinopoisk film and the IMDb ID columns")
+	}
+
+	filmID, ok := ParseKinopoiskFilmID(strings.TrimSpace(row[0]))
+	if !ok {
+		return "", "", fmt.Errorf("invalid kinopoisk film '%s'", row[0])
+	}
+
+	id = TitleID(strings.TrimSpace(row[1]))
+	if id != overrideSkip && !id.IsValid() {
+		return "", "", fmt.Errorf("invalid IMDb ID '%s'", row[1])
+	}
+
+	return filmID, id, nil
+}
+
+// Get returns the overridden ID for the query.
+// The ok is false if there is no override, the ErrSkipped is returned if film is marked to be skipped.
+func (o Overrides) Get(query Query) (id TitleID, ok bool, err error) {
+	filmID := query.KinopoiskFilmID()
+	if filmID == "" {
+		return "", false, nil
+	}
+
+	id, ok = o[filmID]
+	if !ok {
+		return "", false, nil
+	}
+
+	if id == overrideSkip {
+		return "", true, ErrSkipped
+	}
+
+	return id, true, nil
+}
+
+// GetID implements the DataLoader to be used as a resolvers chain link.
+func (o Overrides) GetID(_ context.Context, query Query) (TitleID, Match, error) {
+	match := Match{Source: SourceOverride}
+
+	id, ok, err := o.Get(query)
+	if !ok {
+		return "", match, fmt.Errorf("no override: %w", ErrNotFound)
+	}
+
+	if err != nil {
+		return "", match, err
+	}
+
+	match.Confidence = 1
+
+	return id, match, nil
+}
+
+// Set sets the IMDb ID for the kinopoisk film.
+func (o Overrides) Set(filmID string, id TitleID) {
+	o[filmID] = id
+}
+
+// Skip marks the kinopoisk film to be skipped.
+func (o Overrides) Skip(filmID string) {
+	o[filmID] = overrideSkip
+}
+
+// Save writes the overrides to the CSV file.
+// If the file exists, its comments, header and unparsed rows are kept as is,
+// the changed overrides are updated in place and the new ones are appended.
+// The film's last row is updated if it is duplicated, as it's the one LoadOverrides uses.
+func (o Overrides) Save(targetPath string) error {
+	lines, err := readOverridesLines(targetPath)
+	if err != nil {
+		return err
+	}
+
+	rows := make(map[string][]string, len(o))
+	lastLines := make(map[string]int, len(o))
+
+	for i, line := range lines {
+		if row, filmID, ok := parseOverridesLine(line); ok {
+			rows[filmID] = row
+			lastLines[filmID] = i
+		}
+	}
+
+	for filmID, i := range lastLines {
+		id, ok := o[filmID]
+		if !ok {
+			continue
+		}
+
+		row := rows[filmID]
+
+		if TitleID(strings.TrimSpace(row[1])) == id {
+			continue
+		}
+
+		row[1] = id.String()
+
+		if lines[i], err = formatOverridesRow(row); err != nil {
+			return fmt.Errorf("failed to format override for %s: %w", filmID, err)
+		}
+	}
+
+	if lines == nil {
+		lines = append(lines, "kinopoisk,imdb")
+	}
+
+	filmIDs := make([]string, 0, len(o))
+	for filmID := range o {
+		if _, ok := lastLines[filmID]; !ok {
+			filmIDs = append(filmIDs, filmID)
+		}
+	}
+
+	sort.Strings(filmIDs)
+
+	for _, filmID := range filmIDs {
+		line, err := formatOverridesRow([]string{filmID, string(o[filmID])})
+		if err != nil {
+			return fmt.Errorf("failed to format override for %s: %w", filmID, err)
+		}
+
+		lines = append(lines, line)
+	}
+
+	err = utils.WriteFileAtomic(targetPath, 0644, func(w io.Writer) error {
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write overrides file %s: %w", targetPath, err)
+	}
+
+	return nil
+}
+
+// readOverridesLines returns the lines of the existing overrides file or nil if there is no file.
+func readOverridesLines(sourcePath string) ([]string, error) {
+	content, err := os.ReadFile(sourcePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overrides file %s: %w", sourcePath, err)
+	}
+
+	text := strings.TrimRight(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil, nil
+	}
+
+	return strings.Split(text, "\n"), nil
+}
+
+// parseOverridesLine parses the overrides file line the same way as LoadOverrides does.
+// The ok is false for comments, headers and rows LoadOverrides skips.
+func parseOverridesLine(line string) (row []string, filmID string, ok bool) {
+	if strings.HasPrefix(line, "#") {
+		return nil, "", false
+	}
+
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	row, err := reader.Read()
+	if err != nil {
+		return nil, "", false
+	}
+
+	filmID, _, err = parseOverridesRow(row)
+	if err != nil {
+		return nil, "", false
+	}
+
+	return row, filmID, true
+}
+
+func formatOverridesRow(row []string) (string, error) {
+	buf := &strings.Builder{}
+	writer := csv.NewWriter(buf)
+
+	if err := writer.Write(row); err != nil {
+		return "", err
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
@@ -0,0 +1,118 @@
+package imdb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+
+	overrides, err := imdb.LoadOverrides(zap.New(core), "./testdata/overrides.csv")
+
+	require.NoError(t, err)
+	require.Len(t, overrides, 3)
+
+	// The header is skipped silently, the invalid row is reported.
+	require.Equal(t, 1, logs.Len())
+	assert.Contains(t, logs.All()[0].Message, "line 6")
+	assert.Contains(t, logs.All()[0].Message, "invalid")
+
+	tests := []struct {
+		URL        string
+		ExpectedID imdb.TitleID
+		ExpectedOK bool
+		IsSkipped  bool
+	}{
+		{"https://www.kinopoisk.ru/film/4910679/", "tt17009710", true, false},
+		{"https://www.kinopoisk.ru/film/474953/", "", true, true},
+		{"https://www.kinopoisk.ru/film/42/", "tt0000042", true, false},
+		{"https://www.kinopoisk.ru/film/100/", "", false, false},
+		{"", "", false, false},
+	}
+
+	for i, test := range tests {
+		id, ok, err := overrides.Get(imdb.Query{KinopoiskURL: test.URL})
+
+		assert.Equal(t, test.ExpectedID, id, i)
+		assert.Equal(t, test.ExpectedOK, ok, i)
+		assert.Equal(t, test.IsSkipped, errors.Is(err, imdb.ErrSkipped), i)
+	}
+}
+
+func TestOverrides_Save(t *testing.T) {
+	source, err := os.ReadFile("./testdata/overrides.csv")
+	require.NoError(t, err)
+
+	targetPath := filepath.Join(t.TempDir(), "overrides.csv")
+	require.NoError(t, os.WriteFile(targetPath, source, 0644))
+
+	overrides, err := imdb.LoadOverrides(zap.NewNop(), targetPath)
+	require.NoError(t, err)
+
+	overrides.Set("42", "tt0000043")
+	overrides.Set("7", "tt0000007")
+	overrides.Skip("5")
+
+	require.NoError(t, overrides.Save(targetPath))
+
+	saved, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+
+	expected := "kinopoisk,imdb\n" +
+		"# The remake is found instead of the original film.\n" +
+		"https://www.kinopoisk.ru/film/4910679/,tt17009710\n" +
+		"/film/474953/,skip\n" +
+		"42,tt0000043\n" +
+		"100,invalid\n" +
+		"5,skip\n" +
+		"7,tt0000007\n"
+
+	assert.Equal(t, expected, string(saved))
+
+	reloaded, err := imdb.LoadOverrides(zap.NewNop(), targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, overrides, reloaded)
+}
+
+func TestOverrides_Save_WhenNoFile(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "overrides.csv")
+
+	overrides := imdb.Overrides{"42": "tt0000042"}
+	overrides.Skip("5")
+
+	require.NoError(t, overrides.Save(targetPath))
+
+	saved, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "kinopoisk,imdb\n42,tt0000042\n5,skip\n", string(saved))
+}
+
+func TestOverrides_Save_Duplicates(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "overrides.csv")
+	require.NoError(t, os.WriteFile(targetPath, []byte("42,tt0000001\n42,tt0000002\n"), 0644))
+
+	overrides, err := imdb.LoadOverrides(zap.NewNop(), targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt0000002"), overrides["42"])
+
+	overrides.Set("42", "tt0000003")
+	require.NoError(t, overrides.Save(targetPath))
+
+	saved, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, "42,tt0000001\n42,tt0000003\n", string(saved))
+
+	reloaded, err := imdb.LoadOverrides(zap.NewNop(), targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, overrides, reloaded)
+}
@@ -0,0 +1,37 @@
+package imdb
+
+import "regexp"
+
+var kinopoiskFilmIDRx = regexp.MustCompile(`^(?:.*/(?:film|series)/)?([0-9]+)/?$`)
+
+// Query is a data to find the IMDb title ID by.
+type Query struct {
+	// Title is a title to search by, in the "Original title (year)" format.
+	Title string
+
+	// Names are the film names to match the candidates with, without a year.
+	Names []string
+
+	// Year is a film release year (optional).
+	Year string
+
+	// KinopoiskURL is a URL of the kinopoisk film page (optional).
+	KinopoiskURL string
+}
+
+// KinopoiskFilmID returns the kinopoisk film ID parsed from the KinopoiskURL.
+func (q Query) KinopoiskFilmID() string {
+	id, _ := ParseKinopoiskFilmID(q.KinopoiskURL)
+
+	return id
+}
+
+// ParseKinopoiskFilmID returns the film ID from the kinopoisk film URL or the ID itself.
+func ParseKinopoiskFilmID(val string) (string, bool) {
+	match := kinopoiskFilmIDRx.FindStringSubmatch(val)
+	if match == nil {
+		return "", false
+	}
+
+	return match[1], true
+}
@@ -0,0 +1,181 @@
+package imdb
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	scoreWeightTitle    = 0.4
+	scoreWeightYear     = 0.35
+	scoreWeightPosition = 0.25
+)
+
+// DefaultMinConfidence is a default minimal score of the candidate to be accepted.
+const DefaultMinConfidence = 0.5
+
+// localizedTitleScoreValue is a score of the candidate guessed without any title evidence.
+// It is below the DefaultMinConfidence, so the guess is not accepted,
+// but reported as the best candidate of the unmatched vote to be confirmed by user.
+const localizedTitleScoreValue = 0.45
+
+// typeFactors are the score multipliers for the title types; feature films have an empty type.
+var typeFactors = map[string]float64{
+	"":               1,
+	"Movie":          1,
+	"TV Movie":       0.95,
+	"TV Series":      0.85,
+	"TV Mini Series": 0.85,
+	"TV Special":     0.8,
+	"Short":          0.8,
+	"TV Short":       0.8,
+	"Video":          0.8,
+}
+
+const otherTypeFactor = 0.3
+
+var articles = map[string]struct{}{
+	"a": {}, "an": {}, "the": {},
+	"l": {}, "le": {}, "la": {}, "les": {}, "un": {}, "une": {},
+	"der": {}, "die": {}, "das": {}, "ein": {}, "eine": {},
+	"el": {}, "los": {}, "las": {}, "il": {}, "lo": {}, "gli": {},
+}
+
+var diacriticsRemover = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// ScoreCandidate returns the confidence from 0 to 1 of the candidate to be the queried title.
+func ScoreCandidate(query Query, candidate Candidate) float64 {
+	titleScore := titleSimilarity(query, candidate)
+
+	positionScore := 0.0
+	if candidate.Position > 0 {
+		positionScore = 1 / float64(candidate.Position)
+	}
+
+	year := yearScore(query.Year, candidate.Year)
+
+	var score float64
+
+	if titleScore == 0 {
+		score = localizedTitleScore(candidate, year)
+	} else {
+		// The titles in the same language must match, the year and position only count as much as they do.
+		score = scoreWeightTitle*titleScore + titleScore*(scoreWeightYear*year+scoreWeightPosition*positionScore)
+	}
+
+	return score * typeFactor(candidate)
+}
+
+// titleSimilarity returns the best similarity of the queried names to the candidate's title.
+func titleSimilarity(query Query, candidate Candidate) float64 {
+	names := query.Names
+	if len(names) == 0 {
+		names = []string{query.Title}
+	}
+
+	score := 0.0
+	candidateTokens := tokenize(candidate.Title)
+
+	for _, name := range names {
+		if sim := similarity(tokenize(name), candidateTokens); sim > score {
+			score = sim
+		}
+	}
+
+	return score
+}
+
+func typeFactor(candidate Candidate) float64 {
+	if factor, ok := typeFactors[candidate.Type]; ok {
+		return factor
+	}
+
+	return otherTypeFactor
+}
+
+// localizedTitleScore returns the score of the candidate sharing no words with the queried names.
+// It is usual for IMDb to show the translated title of the film found by the original one,
+// so the exact year at the top of the search results makes the candidate a guess to be confirmed by user.
+func localizedTitleScore(candidate Candidate, year float64) float64 {
+	if candidate.Position != 1 || year < 1 {
+		return 0
+	}
+
+	return localizedTitleScoreValue
+}
+
+// NormalizeTitle lowercases the title, removes the diacritics, punctuation and articles.
+func NormalizeTitle(title string) string {
+	return strings.Join(tokenize(title), " ")
+}
+
+func tokenize(title string) []string {
+	title = strings.ToLower(title)
+	title = strings.ReplaceAll(title, "ё", "е")
+
+	if normalized, _, err := transform.String(diacriticsRemover, title); err == nil {
+		title = normalized
+	}
+
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if _, ok := articles[word]; ok {
+			continue
+		}
+
+		tokens = append(tokens, word)
+	}
+
+	return tokens
+}
+
+// similarity returns the Dice coefficient of the token sets.
+func similarity(a []string, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := make(map[string]int, len(a))
+	for _, token := range a {
+		set[token]++
+	}
+
+	common := 0
+
+	for _, token := range b {
+		if set[token] > 0 {
+			set[token]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+func yearScore(queryYear string, candidateYear string) float64 {
+	qy, err1 := strconv.Atoi(queryYear)
+	cy, err2 := strconv.Atoi(candidateYear)
+
+	if err1 != nil || err2 != nil {
+		return 0.5
+	}
+
+	switch diff := qy - cy; {
+	case diff == 0:
+		return 1
+	case diff == 1 || diff == -1:
+		return 0.5
+	default:
+		return 0
+	}
+}
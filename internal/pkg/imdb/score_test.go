@@ -0,0 +1,79 @@
+package imdb_test
+
+import (
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"The Matrix", "matrix"},
+		{"Anatomie d'une chute", "anatomie d chute"},
+		{"Amélie", "amelie"},
+		{"Ёлки", "елки"},
+		{"Sherlock Holmes: A Game of Shadows", "sherlock holmes game of shadows"},
+	}
+
+	for i, test := range tests {
+		assert.Equal(t, test.Expected, imdb.NormalizeTitle(test.Input), i)
+	}
+}
+
+func TestScoreCandidate(t *testing.T) {
+	query := imdb.Query{
+		Title: "Anatomie d'une chute (2023)",
+		Names: []string{"Anatomie d'une chute", "Анатомия падения"},
+		Year:  "2023",
+	}
+
+	film := imdb.Candidate{ID: "tt17009710", Title: "Anatomie d'une chute", Year: "2023", Position: 1}
+	podcast := imdb.Candidate{
+		ID:       "tt28024119",
+		Title:    "CANNES 2023 - Jour 6: Le Livre des Solutions, Anatomie d'une chute",
+		Year:     "2023",
+		Type:     "Podcast Episode",
+		Position: 2,
+	}
+	remake := imdb.Candidate{ID: "tt0000001", Title: "Anatomie d'une chute", Year: "1990", Position: 3}
+	localized := imdb.Candidate{ID: "tt17009710", Title: "Anatomy of a Fall", Year: "2023", Position: 1}
+
+	filmScore := imdb.ScoreCandidate(query, film)
+
+	assert.GreaterOrEqual(t, filmScore, imdb.DefaultMinConfidence)
+	assert.Greater(t, filmScore, imdb.ScoreCandidate(query, podcast))
+	assert.Greater(t, filmScore, imdb.ScoreCandidate(query, remake))
+	assert.Greater(t, filmScore, imdb.ScoreCandidate(query, localized))
+}
+
+func TestScoreCandidate_WhenTitleLocalized(t *testing.T) {
+	query := imdb.Query{
+		Title: "Anatomie d'une chute (2023)",
+		Names: []string{"Anatomie d'une chute", "Анатомия падения"},
+		Year:  "2023",
+	}
+
+	// The localized title is a guess without the title evidence: it is never accepted by default,
+	// but it's the best candidate to be reported to user.
+	guess := imdb.Candidate{Title: "Anatomy of a Fall", Year: "2023", Position: 1}
+	guessScore := imdb.ScoreCandidate(query, guess)
+
+	assert.Greater(t, guessScore, float64(0))
+	assert.Less(t, guessScore, imdb.DefaultMinConfidence)
+
+	others := []imdb.Candidate{
+		{Title: "Anatomy of a Fall", Year: "2023", Position: 2},
+		{Title: "Anatomy of a Fall", Year: "2022", Position: 1},
+		{Title: "Anatomy of a Fall", Year: "2023", Type: "Podcast Episode", Position: 1},
+		// The same language title is not taken for a translation.
+		{Title: "Chute libre", Year: "2023", Position: 1},
+	}
+
+	for i, candidate := range others {
+		assert.Less(t, imdb.ScoreCandidate(query, candidate), guessScore, i)
+	}
+}
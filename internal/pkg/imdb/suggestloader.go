@@ -0,0 +1,193 @@
+package imdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"go.uber.org/zap"
+)
+
+func NewSuggestDataLoader(
+	log *zap.Logger,
+	downloader downloader.Downloader,
+	baseURL string,
+	minConfidence float64,
+) DataLoader {
+	return &suggestDataLoader{
+		log:           log.With(zap.String("who", "imdb.suggestDataLoader")),
+		downloader:    downloader,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		minConfidence: minConfidence,
+	}
+}
+
+// suggestDataLoader resolves the IMDb IDs using the IMDb JSON suggestion endpoint.
+type suggestDataLoader struct {
+	log           *zap.Logger
+	downloader    downloader.Downloader
+	baseURL       string
+	minConfidence float64
+}
+
+func (d *suggestDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Match{}, err
+	}
+
+	return d.getIDByTitle(ctx, query)
+}
+
+// getIDByTitle searches by each of the film names until the confident suggestion is found,
+// as IMDb may know the film by its original or by its translated name only.
+func (d *suggestDataLoader) getIDByTitle(ctx context.Context, query Query) (TitleID, Match, error) {
+	names := query.Names
+	if len(names) == 0 {
+		names = []string{query.Title}
+	}
+
+	var (
+		match = Match{Source: SourceSuggest}
+		errs  = make([]error, 0, len(names))
+		seen  = make(map[string]struct{}, len(names))
+	)
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+
+		seen[name] = struct{}{}
+
+		id, nameMatch, err := d.getIDByName(ctx, query, name)
+		if err == nil {
+			return id, nameMatch, nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", match, ctxErr
+		}
+
+		if nameMatch.Confidence >= match.Confidence {
+			match = nameMatch
+		}
+
+		errs = append(errs, fmt.Errorf("'%s': %w", name, err))
+	}
+
+	if len(errs) == 0 {
+		return "", match, fmt.Errorf("no title to search by: %w", ErrNotFound)
+	}
+
+	// The title is not found only if none of the searches failed, otherwise the failures are returned.
+	failures := make([]error, 0, len(errs))
+
+	for _, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			failures = append(failures, err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", match, errors.Join(failures...)
+	}
+
+	return "", match, errors.Join(errs...)
+}
+
+func (d *suggestDataLoader) getIDByName(ctx context.Context, query Query, name string) (TitleID, Match, error) {
+	match := Match{Source: SourceSuggest}
+
+	body, err := d.downloader.Download(ctx, d.baseURL+"/suggestion/x/"+url.PathEscape(name)+".json")
+	if err != nil {
+		return "", match, err
+	}
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	var suggestions suggestResponse
+
+	if err := jsoniter.NewDecoder(body).Decode(&suggestions); err != nil {
+		return "", match, fmt.Errorf("failed to decode suggestions: %w", err)
+	}
+
+	candidates := suggestions.candidates()
+	if len(candidates) == 0 {
+		return "", match, fmt.Errorf("no item: %w", ErrNotFound)
+	}
+
+	best, score := pickBestCandidate(query, candidates)
+
+	match.Confidence = score
+	match.Candidates = len(candidates)
+
+	d.log.Debug(
+		"Best candidate picked",
+		zap.String("title", query.Title),
+		zap.String("name", name),
+		zap.String("candidate", best.Title),
+		zap.String("id", best.ID.String()),
+		zap.Float64("score", score),
+		zap.Int("candidates", len(candidates)),
+	)
+
+	if score < d.minConfidence {
+		return "", match, fmt.Errorf(
+			"no confident suggestion, best is %s '%s' (%s) with score %.2f: %w",
+			best.ID.String(), best.Title, best.Year, score, ErrNotFound,
+		)
+	}
+
+	return best.ID, match, nil
+}
+
+type suggestResponse struct {
+	Items []suggestItem `json:"d"`
+}
+
+type suggestItem struct {
+	ID     string `json:"id"`
+	Title  string `json:"l"`
+	Year   int    `json:"y"`
+	TypeID string `json:"qid"`
+}
+
+// candidates returns the title suggestions as the candidates, the names and other items are skipped.
+func (r suggestResponse) candidates() []Candidate {
+	candidates := make([]Candidate, 0, len(r.Items))
+
+	for _, item := range r.Items {
+		id := TitleID(item.ID)
+		if !id.IsValid() {
+			continue
+		}
+
+		candidate := Candidate{
+			ID:       id,
+			Title:    item.Title,
+			Type:     item.TypeID,
+			Position: len(candidates) + 1,
+		}
+
+		// Suggestion types are the same as the dataset ones.
+		if kind, ok := datasetTitleTypes[item.TypeID]; ok {
+			candidate.Type = kind
+		}
+
+		if item.Year > 0 {
+			candidate.Year = strconv.Itoa(item.Year)
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates
+}
@@ -0,0 +1,79 @@
+package imdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestDataLoader_GetID(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/suggestion/x/anatomie d'une chute.json", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./testdata/suggestion.json")
+	})
+	mux.HandleFunc("/suggestion/x/nothing.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"d":[],"q":"nothing","v":1}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	log := logger.NewDefaultConsoleLogger(true)
+	dwn := downloader.NewStdDownloader(log, time.Second, nil)
+	query := imdb.Query{
+		Title: "Anatomie d'une chute (2023)",
+		Names: []string{"Anatomie d'une chute", "Анатомия падения"},
+		Year:  "2023",
+	}
+
+	// The localized title without any title evidence is a guess reported to user, not accepted by default.
+	_, match, err := imdb.NewSuggestDataLoader(log, dwn, server.URL+"/", imdb.DefaultMinConfidence).
+		GetID(context.Background(), imdb.Query{Title: query.Title, Names: query.Names[:1], Year: query.Year})
+
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+	assert.ErrorContains(t, err, "tt17009710")
+	assert.Less(t, match.Confidence, imdb.DefaultMinConfidence)
+
+	loader := imdb.NewSuggestDataLoader(log, dwn, server.URL+"/", 0.4)
+
+	id, match, err := loader.GetID(context.Background(), query)
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+	assert.Equal(t, imdb.SourceSuggest, match.Source)
+	assert.Equal(t, 3, match.Candidates)
+
+	// The next name is searched if nothing is found by the first one.
+	id, _, err = loader.GetID(context.Background(), imdb.Query{
+		Title: "Anatomy of a Fall (2023)",
+		Names: []string{"Nothing", "Anatomie d'une chute"},
+		Year:  "2023",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, imdb.TitleID("tt17009710"), id)
+
+	_, _, err = loader.GetID(context.Background(), imdb.Query{Title: "Nothing (2020)", Names: []string{"Nothing"}})
+
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+
+	_, _, err = loader.GetID(context.Background(), imdb.Query{Title: "Missing (2020)", Names: []string{"Missing"}})
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, imdb.ErrNotFound))
+
+	// The failed search is not taken for the not found title.
+	_, _, err = loader.GetID(context.Background(), imdb.Query{Title: "Missing (2020)", Names: []string{"Nothing", "Missing"}})
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, imdb.ErrNotFound))
+}
@@ -5,5 +5,17 @@ import "time"
 const (
 	Host = "https://www.imdb.com"
 
-	TimeoutFind = 60 * time.Second
+	// WikidataEndpoint is a default Wikidata SPARQL endpoint.
+	WikidataEndpoint = "https://query.wikidata.org/sparql"
+
+	// WikidataUserAgent is a User-Agent of the Wikidata requests,
+	// the Wikidata Query Service throttles the clients without the descriptive one.
+	WikidataUserAgent = "kpvotes (https://github.com/kukymbr/kinopoiskexport)"
+
+	// SuggestURL is a default base URL of the IMDb suggestion endpoint.
+	SuggestURL = "https://v3.sg.media-imdb.com"
+
+	TimeoutFind     = 60 * time.Second
+	TimeoutSuggest  = 30 * time.Second
+	TimeoutWikidata = 60 * time.Second
 )
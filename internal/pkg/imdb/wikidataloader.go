@@ -0,0 +1,347 @@
+package imdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"go.uber.org/zap"
+)
+
+// wikidataBatchSize is a max number of the kinopoisk film IDs requested in one SPARQL query.
+const wikidataBatchSize = 100
+
+// NewWikidataDataLoader creates the DataLoader resolving IMDb IDs by the kinopoisk film IDs
+// using the Wikidata properties P2603 (kinopoisk film ID) and P345 (IMDb ID).
+// The endpoint is a SPARQL endpoint URL or a path to the local file
+// with the SPARQL JSON results having the "kp" and "imdb" variables.
+// The downloader must send the WikidataUserAgent to the Wikidata Query Service.
+func NewWikidataDataLoader(log *zap.Logger, downloader downloader.Downloader, endpoint string) DataLoader {
+	return &wikidataDataLoader{
+		log:        log.With(zap.String("who", "imdb.wikidataDataLoader"), zap.String("endpoint", endpoint)),
+		downloader: downloader,
+		endpoint:   endpoint,
+		batchSize:  wikidataBatchSize,
+		known:      make(map[string][]TitleID),
+	}
+}
+
+// wikidataDataLoader resolves each batch of the kinopoisk film IDs with a single SPARQL query.
+// The first ID is queried at once, the IDs requested while the query is running
+// are collected into the next batch, which is sent as soon as the query is done or the batch is full.
+type wikidataDataLoader struct {
+	log        *zap.Logger
+	downloader downloader.Downloader
+	endpoint   string
+	batchSize  int
+
+	mu       sync.Mutex
+	known    map[string][]TitleID
+	pending  *wikidataBatch
+	inFlight int
+
+	dumpOnce sync.Once
+	dumpErr  error
+}
+
+// wikidataBatch is a set of the film IDs queried together.
+// Its query is canceled when all the requests waiting for it are gone.
+type wikidataBatch struct {
+	filmIDs map[string]struct{}
+	waiters int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	done chan struct{}
+	err  error
+}
+
+func (d *wikidataDataLoader) GetID(ctx context.Context, query Query) (TitleID, Match, error) {
+	if err := ctx.Err(); err != nil {
+		return "", Match{}, err
+	}
+
+	match := Match{Source: SourceWikidata}
+
+	filmID := query.KinopoiskFilmID()
+	if filmID == "" {
+		return "", match, fmt.Errorf("no kinopoisk film ID: %w", ErrNotFound)
+	}
+
+	var err error
+
+	if d.isLocalDump() {
+		err = d.loadDump()
+	} else {
+		err = d.load(ctx, filmID)
+	}
+
+	if err != nil {
+		return "", match, fmt.Errorf("failed to query wikidata: %w", err)
+	}
+
+	d.mu.Lock()
+	ids := d.known[filmID]
+	d.mu.Unlock()
+
+	match.Candidates = len(ids)
+
+	if len(ids) == 0 {
+		return "", match, fmt.Errorf("no wikidata item for kinopoisk film %s: %w", filmID, ErrNotFound)
+	}
+
+	if len(ids) > 1 {
+		return "", match, fmt.Errorf(
+			"ambiguous wikidata items for kinopoisk film %s: %s: %w",
+			filmID, joinTitleIDs(ids), ErrNotFound,
+		)
+	}
+
+	match.Confidence = 1
+
+	return ids[0], match, nil
+}
+
+func (d *wikidataDataLoader) isLocalDump() bool {
+	return !strings.HasPrefix(d.endpoint, "http://") && !strings.HasPrefix(d.endpoint, "https://")
+}
+
+// load waits until the film ID is resolved by one of the batches.
+func (d *wikidataDataLoader) load(ctx context.Context, filmID string) error {
+	d.mu.Lock()
+
+	if _, ok := d.known[filmID]; ok {
+		d.mu.Unlock()
+
+		return nil
+	}
+
+	batch := d.pending
+	if batch == nil {
+		batch = newWikidataBatch(ctx)
+		d.pending = batch
+	}
+
+	batch.filmIDs[filmID] = struct{}{}
+	batch.waiters++
+
+	if d.inFlight == 0 || len(batch.filmIDs) >= d.batchSize {
+		d.sendPending()
+	} else {
+		d.log.Debug("Waiting for the running wikidata query", zap.String("film_id", filmID))
+	}
+
+	d.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		d.leave(batch)
+
+		return ctx.Err()
+	case <-batch.done:
+		return batch.err
+	}
+}
+
+func newWikidataBatch(ctx context.Context) *wikidataBatch {
+	// The batch is shared by the requests, so it is canceled only when none of them waits for it.
+	batchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	return &wikidataBatch{
+		filmIDs: make(map[string]struct{}),
+		ctx:     batchCtx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
+
+// leave removes the canceled request from the batch waiters and cancels the batch if it was the last one.
+func (d *wikidataDataLoader) leave(batch *wikidataBatch) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	batch.waiters--
+	if batch.waiters > 0 {
+		return
+	}
+
+	if d.pending == batch {
+		d.pending = nil
+	}
+
+	batch.cancel()
+}
+
+// sendPending starts the query of the pending batch, the mu must be locked.
+func (d *wikidataDataLoader) sendPending() {
+	batch := d.pending
+	d.pending = nil
+	d.inFlight++
+
+	go d.flush(batch)
+}
+
+func (d *wikidataDataLoader) flush(batch *wikidataBatch) {
+	filmIDs := make([]string, 0, len(batch.filmIDs))
+	for filmID := range batch.filmIDs {
+		filmIDs = append(filmIDs, filmID)
+	}
+
+	sort.Strings(filmIDs)
+
+	d.log.Debug(fmt.Sprintf("Querying wikidata for %d kinopoisk film(s)", len(filmIDs)))
+
+	ids, err := d.query(batch.ctx, filmIDs)
+
+	batch.cancel()
+
+	d.mu.Lock()
+
+	if err == nil {
+		for _, filmID := range filmIDs {
+			d.known[filmID] = ids[filmID]
+		}
+	}
+
+	d.inFlight--
+
+	if d.pending != nil {
+		d.sendPending()
+	}
+
+	d.mu.Unlock()
+
+	batch.err = err
+	close(batch.done)
+}
+
+func (d *wikidataDataLoader) query(ctx context.Context, filmIDs []string) (map[string][]TitleID, error) {
+	values := url.Values{}
+	values.Set("query", buildWikidataQuery(filmIDs))
+	values.Set("format", "json")
+
+	separator := "?"
+	if strings.Contains(d.endpoint, "?") {
+		separator = "&"
+	}
+
+	body, err := d.downloader.Download(ctx, d.endpoint+separator+values.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	return parseWikidataResults(body)
+}
+
+func (d *wikidataDataLoader) loadDump() error {
+	d.dumpOnce.Do(func() {
+		f, err := os.Open(d.endpoint)
+		if err != nil {
+			d.dumpErr = fmt.Errorf("failed to open wikidata dump: %w", err)
+
+			return
+		}
+
+		defer func() {
+			_ = f.Close()
+		}()
+
+		ids, err := parseWikidataResults(f)
+		if err != nil {
+			d.dumpErr = err
+
+			return
+		}
+
+		d.mu.Lock()
+		d.known = ids
+		d.mu.Unlock()
+
+		d.log.Debug(fmt.Sprintf("Loaded %d kinopoisk film(s) from wikidata dump", len(ids)))
+	})
+
+	return d.dumpErr
+}
+
+func buildWikidataQuery(filmIDs []string) string {
+	quoted := make([]string, 0, len(filmIDs))
+	for _, filmID := range filmIDs {
+		quoted = append(quoted, `"`+filmID+`"`)
+	}
+
+	return `SELECT ?kp ?imdb WHERE { VALUES ?kp { ` + strings.Join(quoted, " ") + ` } ` +
+		`?item wdt:P2603 ?kp; wdt:P345 ?imdb. }`
+}
+
+type wikidataResults struct {
+	Results struct {
+		Bindings []struct {
+			KP   wikidataValue `json:"kp"`
+			IMDb wikidataValue `json:"imdb"`
+		} `json:"bindings"`
+	} `json:"results"`
+}
+
+type wikidataValue struct {
+	Value string `json:"value"`
+}
+
+// parseWikidataResults reads the SPARQL JSON results into the IMDb IDs by the kinopoisk film IDs.
+func parseWikidataResults(r io.Reader) (map[string][]TitleID, error) {
+	var results wikidataResults
+
+	if err := jsoniter.NewDecoder(r).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode wikidata results: %w", err)
+	}
+
+	ids := make(map[string][]TitleID)
+
+	for _, binding := range results.Results.Bindings {
+		filmID, ok := ParseKinopoiskFilmID(binding.KP.Value)
+		if !ok {
+			continue
+		}
+
+		id := TitleID(binding.IMDb.Value)
+		if !id.IsValid() {
+			continue
+		}
+
+		isKnown := false
+
+		for _, existing := range ids[filmID] {
+			if existing == id {
+				isKnown = true
+
+				break
+			}
+		}
+
+		if !isKnown {
+			ids[filmID] = append(ids[filmID], id)
+		}
+	}
+
+	return ids, nil
+}
+
+func joinTitleIDs(ids []TitleID) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+
+	return strings.Join(values, ", ")
+}
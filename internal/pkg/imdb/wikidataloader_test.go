@@ -0,0 +1,185 @@
+package imdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/downloader"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWikidataDataLoader_GetID(t *testing.T) {
+	dump, err := os.ReadFile("./testdata/wikidata.json")
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query().Get("query"))
+		isFirst := len(queries) == 1
+		mu.Unlock()
+
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+
+		if isFirst {
+			<-release
+		}
+
+		_, _ = w.Write(dump)
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zap.DebugLevel)
+	log := zap.New(core)
+	dwn := downloader.NewStdDownloader(log, time.Second, nil)
+	loader := imdb.NewWikidataDataLoader(log, dwn, server.URL)
+
+	var (
+		wg       sync.WaitGroup
+		found    imdb.TitleID
+		match    imdb.Match
+		foundErr error
+		missErr  error
+		skipErr  error
+	)
+
+	wg.Add(1)
+
+	// The first film is queried at once.
+	go func() {
+		defer wg.Done()
+
+		_, _, skipErr = loader.GetID(context.Background(), imdb.Query{KinopoiskURL: "/film/474953/"})
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(queries) == 1
+	}, time.Second, time.Millisecond)
+
+	wg.Add(2)
+
+	// The films requested while the first query is running are batched together.
+	go func() {
+		defer wg.Done()
+
+		found, match, foundErr = loader.GetID(context.Background(), imdb.Query{
+			Title:        "Anatomie d'une chute (2023)",
+			KinopoiskURL: "https://www.kinopoisk.ru/film/4910679/",
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		_, _, missErr = loader.GetID(context.Background(), imdb.Query{
+			Title:        "Lightyear (2022)",
+			KinopoiskURL: "https://www.kinopoisk.ru/film/4291715/",
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("Waiting for the running wikidata query").Len() == 2
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	require.NoError(t, skipErr)
+	require.NoError(t, foundErr)
+	assert.Equal(t, imdb.TitleID("tt17009710"), found)
+	assert.Equal(t, imdb.SourceWikidata, match.Source)
+	assert.Equal(t, float64(1), match.Confidence)
+	assert.True(t, errors.Is(missErr, imdb.ErrNotFound))
+
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0], `"474953"`)
+	assert.NotContains(t, queries[0], `"4910679"`)
+	assert.Contains(t, queries[1], `"4910679"`)
+	assert.Contains(t, queries[1], `"4291715"`)
+	assert.Contains(t, queries[1], "wdt:P2603")
+
+	_, _, err = loader.GetID(context.Background(), imdb.Query{KinopoiskURL: "/film/4291715/"})
+
+	assert.True(t, errors.Is(err, imdb.ErrNotFound))
+	assert.Len(t, queries, 2)
+}
+
+func TestWikidataDataLoader_GetID_WhenCanceled(t *testing.T) {
+	canceled := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer server.Close()
+
+	log := logger.NewDefaultConsoleLogger(true)
+	loader := imdb.NewWikidataDataLoader(log, downloader.NewStdDownloader(log, time.Minute, nil), server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := loader.GetID(ctx, imdb.Query{KinopoiskURL: "/film/4910679/"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The batch query is canceled as nobody waits for it anymore.
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("wikidata query is not canceled")
+	}
+}
+
+func TestWikidataDataLoader_GetID_FromDump(t *testing.T) {
+	log := logger.NewDefaultConsoleLogger(true)
+	loader := imdb.NewWikidataDataLoader(log, downloader.NewDownloaderFileMock(nil), "./testdata/wikidata.json")
+
+	tests := []struct {
+		URL      string
+		Expected imdb.TitleID
+	}{
+		{URL: "/film/4910679/", Expected: "tt17009710"},
+		{URL: "https://www.kinopoisk.ru/series/474953/", Expected: "tt1515091"},
+		{URL: "/film/1/"},
+		{URL: "/film/4291715/"},
+		{URL: "/film/42/"},
+		{URL: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Trim(test.URL, "/"), func(t *testing.T) {
+			id, _, err := loader.GetID(context.Background(), imdb.Query{KinopoiskURL: test.URL})
+
+			if test.Expected == "" {
+				assert.True(t, errors.Is(err, imdb.ErrNotFound))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, id)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Package blockpage detects the kinopoisk pages served instead of the requested ones
+// when kinopoisk blocks the requests.
+package blockpage
+
+import (
+	"errors"
+
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	// ErrCaptcha is returned if kinopoisk serves the anti-bot captcha page instead of the requested one.
+	ErrCaptcha = errors.New("kinopoisk served the anti-bot captcha page")
+	// ErrLoginRequired is returned if kinopoisk serves the login page instead of the requested one.
+	ErrLoginRequired = errors.New("kinopoisk served the login page")
+)
+
+var captchaXPaths = []string{
+	`//form[contains(@action, "checkcaptcha") or contains(@action, "showcaptcha")]`,
+	`//*[contains(@class, "CheckboxCaptcha") or contains(@class, "AdvancedCaptcha") or contains(@class, "SmartCaptcha")]`,
+	`//script[contains(@src, "smartcaptcha")]`,
+}
+
+var loginXPaths = []string{
+	`//form[contains(@action, "passport.yandex") or contains(@action, "/auth")]`,
+	`//a[contains(@href, "passport.yandex") and contains(@href, "auth")]`,
+}
+
+// Detect returns the ErrCaptcha or ErrLoginRequired if the page is the captcha or login one, nil otherwise.
+// It must be called only if the page misses the expected content, as the regular pages may have the login links.
+func Detect(doc *html.Node) error {
+	if hasAny(doc, captchaXPaths) {
+		return ErrCaptcha
+	}
+
+	if hasAny(doc, loginXPaths) {
+		return ErrLoginRequired
+	}
+
+	return nil
+}
+
+// IsBlocked returns true if the error means kinopoisk blocks the requests.
+func IsBlocked(err error) bool {
+	return errors.Is(err, ErrCaptcha) || errors.Is(err, ErrLoginRequired)
+}
+
+func hasAny(doc *html.Node, xpaths []string) bool {
+	for _, xpath := range xpaths {
+		if node, err := htmlquery.Query(doc, xpath); err == nil && node != nil {
+			return true
+		}
+	}
+
+	return false
+}
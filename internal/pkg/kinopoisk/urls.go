@@ -6,6 +6,9 @@ import (
 
 const (
 	Host = "https://www.kinopoisk.ru"
+	// CookieDomain is a domain of the kinopoisk session cookies.
+	CookieDomain = "kinopoisk.ru"
 
 	TimeoutVotes = 60 * time.Second
+	TimeoutFilm  = 30 * time.Second
 )
@@ -1,6 +1,8 @@
 package kinopoisk
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kukymbr/kinopoiskexport/internal/pkg/imdb"
@@ -16,7 +18,8 @@ type Vote struct {
 
 	Rate uint8
 
-	ImdbID imdb.TitleID
+	ImdbID    imdb.TitleID
+	ImdbMatch imdb.Match
 }
 
 func (v *Vote) GetOriginalTitle() string {
@@ -32,3 +35,45 @@ func (v *Vote) GetOriginalTitle() string {
 
 	return title
 }
+
+// GetAbsoluteMovieURL returns the movie URL including the kinopoisk host.
+func (v *Vote) GetAbsoluteMovieURL() string {
+	if v.MovieURL == "" || strings.HasPrefix(v.MovieURL, "http") {
+		return v.MovieURL
+	}
+
+	return Host + v.MovieURL
+}
+
+// ToIMDbQuery returns the query to find the vote's IMDb title ID.
+func (v *Vote) ToIMDbQuery() imdb.Query {
+	names := make([]string, 0, 2)
+
+	if v.MovieNameOriginal != "" {
+		names = append(names, v.MovieNameOriginal)
+	}
+
+	if v.MovieNameRu != "" {
+		names = append(names, v.MovieNameRu)
+	}
+
+	return imdb.Query{
+		Title:        v.GetOriginalTitle(),
+		Names:        names,
+		Year:         v.MovieYear,
+		KinopoiskURL: v.GetAbsoluteMovieURL(),
+	}
+}
+
+// Validate checks if the vote has all the fields parsed from the kinopoisk page.
+func (v *Vote) Validate() error {
+	if v.MovieURL == "" {
+		return fmt.Errorf("no movie URL in vote item %s", v.MovieNameRu)
+	}
+
+	if v.Rate == 0 {
+		return fmt.Errorf("no movie rate in vote item %s", v.MovieNameRu)
+	}
+
+	return nil
+}
@@ -5,12 +5,8 @@ import "fmt"
 type Votes []Vote
 
 func (v *Votes) Add(vote Vote) error {
-	if vote.MovieURL == "" {
-		return fmt.Errorf("no movie URL in vote item %s", vote.MovieNameRu)
-	}
-
-	if vote.Rate == 0 {
-		return fmt.Errorf("no movie rate in vote item %s", vote.MovieNameRu)
+	if err := vote.Validate(); err != nil {
+		return err
 	}
 
 	if vote.ImdbID == "" {
@@ -31,3 +27,12 @@ func (v *Votes) AddOnce(vote Vote) error {
 
 	return v.Add(vote)
 }
+
+// UnmatchedVote is a vote the IMDb ID was not found for.
+type UnmatchedVote struct {
+	Vote
+
+	Err error
+}
+
+type UnmatchedVotes []UnmatchedVote
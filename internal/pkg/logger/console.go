@@ -8,6 +8,16 @@ import (
 )
 
 func NewDefaultConsoleLogger(isDebug bool) *zap.Logger {
+	return newConsoleLogger(isDebug, os.Stdout)
+}
+
+// NewStderrConsoleLogger creates the console logger writing all the levels to the stderr,
+// leaving the stdout for the command's output.
+func NewStderrConsoleLogger(isDebug bool) *zap.Logger {
+	return newConsoleLogger(isDebug, os.Stderr)
+}
+
+func newConsoleLogger(isDebug bool, lowPriorityOut *os.File) *zap.Logger {
 	minLevel := zap.InfoLevel
 	if isDebug {
 		minLevel = zap.DebugLevel
@@ -21,8 +31,8 @@ func NewDefaultConsoleLogger(isDebug bool) *zap.Logger {
 		return lvl < zapcore.ErrorLevel && lvl >= minLevel
 	})
 
-	consoleDebugging := zapcore.Lock(os.Stdout)
-	consoleErrors := zapcore.Lock(os.Stderr)
+	consoleDebugging := zapcore.Lock(redactWriter{WriteSyncer: lowPriorityOut})
+	consoleErrors := zapcore.Lock(redactWriter{WriteSyncer: os.Stderr})
 
 	encoderConf := zap.NewProductionEncoderConfig()
 	encoderConf.EncodeTime = zapcore.TimeEncoderOfLayout("20060102 15:04:05")
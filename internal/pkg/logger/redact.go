@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedPlaceholder replaces the secrets in the logs.
+const RedactedPlaceholder = "[REDACTED]"
+
+// minSecretLen is a min length of the redacted secret, the shorter values are too common to be replaced.
+const minSecretLen = 4
+
+var secrets = &secretsRedactor{}
+
+// RedactSecrets registers the values replaced with the RedactedPlaceholder in the output of all console loggers,
+// including the ones created before.
+func RedactSecrets(values ...string) {
+	secrets.add(values)
+}
+
+type secretsRedactor struct {
+	mu       sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}
+
+func (r *secretsRedactor) add(values []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, val := range values {
+		if len(val) >= minSecretLen {
+			r.values = append(r.values, val)
+		}
+	}
+
+	if len(r.values) == 0 {
+		return
+	}
+
+	pairs := make([]string, 0, len(r.values)*2)
+	for _, val := range r.values {
+		pairs = append(pairs, val, RedactedPlaceholder)
+	}
+
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+func (r *secretsRedactor) redact(p []byte) []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.replacer == nil {
+		return p
+	}
+
+	return []byte(r.replacer.Replace(string(p)))
+}
+
+// redactWriter is a zapcore.WriteSyncer redacting the secrets from the encoded log entries.
+type redactWriter struct {
+	zapcore.WriteSyncer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.WriteSyncer.Write(secrets.redact(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,41 @@
+package logger_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stderr := os.Stderr
+	os.Stderr = w
+
+	log := logger.NewStderrConsoleLogger(true)
+
+	os.Stderr = stderr
+
+	logger.RedactSecrets("kp-session-secret", "ru")
+
+	log.Info("cookie kp-session-secret is set", zap.String("cookie", "Session_id=kp-session-secret"))
+	log.Error("failed", zap.Error(io.ErrUnexpectedEOF), zap.String("locale", "ru"))
+
+	require.NoError(t, w.Close())
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(out), "kp-session-secret")
+	assert.Contains(t, string(out), "cookie [REDACTED] is set")
+	assert.Contains(t, string(out), "Session_id=[REDACTED]")
+
+	// The too short values are not redacted.
+	assert.Contains(t, string(out), `"locale": "ru"`)
+}
@@ -0,0 +1,46 @@
+package utils_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+
+	err := utils.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+
+		return err
+	})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	err = utils.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "broken")
+
+		return errors.New("failed")
+	})
+	assert.Error(t, err)
+
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
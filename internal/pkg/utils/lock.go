@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile acquires the advisory lock for the path, blocking until it is available.
+// Shared locks are held by the readers, the exclusive one by the writer.
+// The lock is taken on the hidden ".<name>.lock" file next to the path,
+// so the path itself can be atomically replaced while locked.
+// The returned func releases the lock.
+func LockFile(path string, exclusive bool) (unlock func() error, err error) {
+	lockPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", lockPath, err)
+	}
+
+	if err := lockFile(f, exclusive); err != nil {
+		_ = f.Close()
+
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	return func() error {
+		err := unlockFile(f)
+
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+
+		return err
+	}, nil
+}
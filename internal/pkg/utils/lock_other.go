@@ -0,0 +1,14 @@
+//go:build !unix
+
+package utils
+
+import "os"
+
+// lockFile is a no-op: the advisory locking is supported on unix systems only.
+func lockFile(_ *os.File, _ bool) error {
+	return nil
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package utils_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kukymbr/kinopoiskexport/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	unlockShared, err := utils.LockFile(path, false)
+	require.NoError(t, err)
+
+	unlockShared2, err := utils.LockFile(path, false)
+	require.NoError(t, err)
+
+	locked := make(chan struct{})
+
+	go func() {
+		unlock, err := utils.LockFile(path, true)
+		if err == nil {
+			_ = unlock()
+		}
+
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("exclusive lock is acquired while the shared ones are held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, unlockShared())
+	require.NoError(t, unlockShared2())
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("exclusive lock is not acquired after the shared ones are released")
+	}
+
+	assert.FileExists(t, filepath.Join(filepath.Dir(path), ".cache.jsonl.lock"))
+}
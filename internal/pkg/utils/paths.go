@@ -2,6 +2,7 @@ package utils
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -18,3 +19,22 @@ func FixSeparators(path string) string {
 
 	return strings.ReplaceAll(path, string(sepToReplace), string(os.PathSeparator))
 }
+
+// WithSuffix adds the suffix to the file name before its extension.
+func WithSuffix(path string, suffix string) string {
+	path = FixSeparators(path)
+	dir := filepath.Dir(path)
+	filename := filepath.Base(path)
+	ext := filepath.Ext(filename)
+
+	if ext != "" {
+		filename, _ = strings.CutSuffix(filename, ext)
+	}
+
+	return filepath.Join(dir, filename+suffix+ext)
+}
+
+// ReplaceExt replaces the file extension with the ext.
+func ReplaceExt(path string, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
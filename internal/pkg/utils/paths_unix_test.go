@@ -25,3 +25,38 @@ func TestFixSeparators(t *testing.T) {
 		assert.Equal(t, test.Expected, path, i)
 	}
 }
+
+func TestWithSuffix(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Suffix   string
+		Expected string
+	}{
+		{"votes.csv", ".1", "votes.1.csv"},
+		{"/home/user/votes.csv", ".unmatched", "/home/user/votes.unmatched.csv"},
+		{"/home/user/votes", ".unmatched", "/home/user/votes.unmatched"},
+	}
+
+	for i, test := range tests {
+		path := utils.WithSuffix(test.Input, test.Suffix)
+
+		assert.Equal(t, test.Expected, path, i)
+	}
+}
+
+func TestReplaceExt(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Ext      string
+		Expected string
+	}{
+		{"votes.csv", ".audit.json", "votes.audit.json"},
+		{"/home/user.name/votes", ".json", "/home/user.name/votes.json"},
+	}
+
+	for i, test := range tests {
+		path := utils.ReplaceExt(test.Input, test.Ext)
+
+		assert.Equal(t, test.Expected, path, i)
+	}
+}